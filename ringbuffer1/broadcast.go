@@ -0,0 +1,297 @@
+package ringbuffer
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bmkessler/fastdiv"
+)
+
+// A LappedError is returned by BroadcastReader.Read when the reader fell more
+// than a capacity behind a lapping Broadcast, and the oldest bytes it hadn't
+// read yet were overwritten. The reader skips ahead to the oldest data still
+// in the ring, so the next Read continues from there.
+type LappedError struct {
+	Missed int
+}
+
+func (e *LappedError) Error() string {
+	return fmt.Sprintf("ringbuffer: reader lapped, missed %d bytes", e.Missed)
+}
+
+// A Broadcast is a ringbuffer with one writer and any number of readers, each
+// with its own read cursor, so that every reader sees the whole stream
+// without it being copied once per reader.
+//
+// By default the writer is gated by the slowest reader: Write only uses space
+// that every registered reader has read. A Broadcast created with
+// NewLappingBroadcast never blocks the writer instead, and readers that fall
+// too far behind are lapped and told how many bytes they missed.
+//
+// The cursors are free-running uint64s, reduced modulo capacity with fastdiv
+// to index the storage.
+type Broadcast struct {
+	// uint64s first, for 64-bit atomic alignment on 32-bit platforms
+	write   uint64
+	closed  uint32
+	lapping bool
+	buf     []byte
+	n       fastdiv.Uint64
+
+	// serializes the copies in and out of storage, only allocated in
+	// lapping mode, where the writer overwrites what readers copy out
+	storageMu *sync.Mutex
+
+	mu      sync.Mutex   // serializes changes to readers
+	readers atomic.Value // []*BroadcastReader, copy-on-write
+}
+
+// A BroadcastReader is one reader of a Broadcast. Each BroadcastReader may be
+// used by one goroutine at a time.
+type BroadcastReader struct {
+	read uint64
+	b    *Broadcast
+}
+
+var (
+	_ io.WriteCloser = (*Broadcast)(nil)
+	_ io.ReadCloser  = (*BroadcastReader)(nil)
+)
+
+// NewBroadcast creates a broadcast ringbuffer with the specified capacity,
+// whose writer is gated by the slowest reader. It panics with ErrBadCapacity
+// if the capacity isn't positive.
+func NewBroadcast(capacity int) *Broadcast {
+	if capacity <= 0 {
+		panic(ErrBadCapacity)
+	}
+
+	buf := make([]byte, capacity)
+	b := &Broadcast{
+		buf: buf,
+		n:   fastdiv.NewUint64(uint64(len(buf))),
+	}
+	b.readers.Store([]*BroadcastReader(nil))
+	return b
+}
+
+// NewLappingBroadcast creates a broadcast ringbuffer with the specified
+// capacity, whose writer never waits for readers. Readers that fall more than
+// capacity bytes behind get a *LappedError. Like NewBroadcast, it panics with
+// ErrBadCapacity if the capacity isn't positive.
+//
+// The writer may overwrite the bytes a reader is copying out, so in this mode
+// the copies in and out of storage are serialized by a mutex, like in an
+// overwriting Ringbuffer, and the writer and readers are no longer
+// lock-free.
+func NewLappingBroadcast(capacity int) *Broadcast {
+	b := NewBroadcast(capacity)
+	b.lapping = true
+	b.storageMu = new(sync.Mutex)
+	return b
+}
+
+// lockStorage locks storage against the writer in lapping mode. It does
+// nothing otherwise.
+func (b *Broadcast) lockStorage() {
+	if b.storageMu != nil {
+		b.storageMu.Lock()
+	}
+}
+
+func (b *Broadcast) unlockStorage() {
+	if b.storageMu != nil {
+		b.storageMu.Unlock()
+	}
+}
+
+func (b *Broadcast) regions(ptr, count uint64) (first, second []byte) {
+	return split(b.buf, int(b.n.Mod(ptr)), int(count))
+}
+
+func (b *Broadcast) loadReaders() []*BroadcastReader {
+	return b.readers.Load().([]*BroadcastReader)
+}
+
+// slowest returns the read cursor of the reader furthest behind, or write if
+// there are no readers.
+func (b *Broadcast) slowest(write uint64) uint64 {
+	oldest := write
+	for _, rd := range b.loadReaders() {
+		if read := atomic.LoadUint64(&rd.read); read < oldest {
+			oldest = read
+		}
+	}
+	return oldest
+}
+
+// NewReader registers a new reader, which starts reading from the current
+// write position. Readers that are no longer needed must be closed, or they
+// hold up a gated writer.
+func (b *Broadcast) NewReader() *BroadcastReader {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rd := &BroadcastReader{
+		read: atomic.LoadUint64(&b.write),
+		b:    b,
+	}
+
+	readers := b.loadReaders()
+	updated := make([]*BroadcastReader, len(readers), len(readers)+1)
+	copy(updated, readers)
+	b.readers.Store(append(updated, rd))
+
+	return rd
+}
+
+// Readers returns the number of registered readers.
+func (b *Broadcast) Readers() int {
+	return len(b.loadReaders())
+}
+
+// Capacity returns the capacity of the underlying []byte buf.
+func (b *Broadcast) Capacity() int {
+	return len(b.buf)
+}
+
+// Write copies buf into the ringbuffer for every reader to read.
+//
+// A gated Broadcast stores as much of buf as the slowest reader has made
+// room for, and returns a *CapacityError along with the short count if that
+// isn't all of it. A lapping Broadcast always accepts all of buf, overwriting
+// data that readers haven't read yet; if buf is larger than the capacity
+// only its last Capacity() bytes are stored. Writing to a closed Broadcast
+// returns ErrClosed.
+func (b *Broadcast) Write(buf []byte) (n int, err error) {
+	if atomic.LoadUint32(&b.closed) == 1 {
+		return 0, ErrClosed
+	}
+
+	capacity := uint64(len(b.buf))
+	// only the writer moves write, so it can be loaded once
+	write := atomic.LoadUint64(&b.write)
+
+	if b.lapping {
+		n = len(buf)
+		end := write + uint64(n)
+		if uint64(n) > capacity {
+			// the cursors still advance past the skipped bytes, so
+			// readers count them as missed
+			buf = buf[uint64(n)-capacity:]
+		}
+
+		b.lockStorage()
+		first, second := b.regions(end-uint64(len(buf)), uint64(len(buf)))
+		copy(first, buf)
+		copy(second, buf[len(first):])
+		atomic.StoreUint64(&b.write, end)
+		b.unlockStorage()
+
+		return n, nil
+	}
+
+	emptyCount := capacity - (write - b.slowest(write))
+	if uint64(len(buf)) > emptyCount {
+		err = &CapacityError{Requested: len(buf), Available: int(emptyCount)}
+		buf = buf[:emptyCount]
+	}
+	if len(buf) == 0 {
+		return 0, err
+	}
+
+	first, second := b.regions(write, uint64(len(buf)))
+	copy(first, buf)
+	copy(second, buf[len(first):])
+	atomic.StoreUint64(&b.write, write+uint64(len(buf)))
+
+	return len(buf), err
+}
+
+// Close marks the Broadcast as closed. Subsequent writes return ErrClosed,
+// and once a reader has read the remaining data, its Read returns io.EOF.
+func (b *Broadcast) Close() error {
+	atomic.StoreUint32(&b.closed, 1)
+	return nil
+}
+
+// Size returns the number of bytes written that this reader hasn't read yet.
+// For a lapping Broadcast it can be larger than the capacity, if the reader
+// has been lapped.
+func (rd *BroadcastReader) Size() int {
+	read := atomic.LoadUint64(&rd.read)
+	write := atomic.LoadUint64(&rd.b.write)
+	if read >= write {
+		return 0
+	}
+	return int(write - read)
+}
+
+// Read fills buf with as much of the data this reader hasn't read yet as can
+// fit. Read never blocks. If there is no new data it returns 0, ErrEmpty,
+// unless the Broadcast has been closed, in which case it returns 0, io.EOF.
+// A zero-length buf returns 0, nil.
+//
+// For a lapping Broadcast, if the reader has been lapped Read returns 0 and a
+// *LappedError, and moves the reader up to the oldest data in the ring.
+func (rd *BroadcastReader) Read(buf []byte) (n int, err error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	b := rd.b
+	capacity := uint64(len(b.buf))
+
+	b.lockStorage()
+	defer b.unlockStorage()
+
+	closed := atomic.LoadUint32(&b.closed) == 1
+	// only this reader moves read, so it can be loaded once
+	read := atomic.LoadUint64(&rd.read)
+	write := atomic.LoadUint64(&b.write)
+
+	if b.lapping && write-read > capacity {
+		missed := write - capacity - read
+		atomic.StoreUint64(&rd.read, read+missed)
+		return 0, &LappedError{Missed: int(missed)}
+	}
+
+	if read >= write {
+		if closed {
+			return 0, io.EOF
+		}
+		return 0, ErrEmpty
+	}
+
+	readCount := write - read
+	if readCount > uint64(len(buf)) {
+		readCount = uint64(len(buf))
+	}
+
+	first, second := b.regions(read, readCount)
+	copy(buf, first)
+	copy(buf[len(first):], second)
+
+	atomic.StoreUint64(&rd.read, read+readCount)
+	return int(readCount), nil
+}
+
+// Close unregisters the reader, so it no longer holds up a gated writer. The
+// reader must not be used after Close.
+func (rd *BroadcastReader) Close() error {
+	b := rd.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	readers := b.loadReaders()
+	updated := make([]*BroadcastReader, 0, len(readers))
+	for _, other := range readers {
+		if other != rd {
+			updated = append(updated, other)
+		}
+	}
+	b.readers.Store(updated)
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package ringbuffer_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestBroadcastEveryReaderSeesAll(t *testing.T) {
+	b := ringbuffer.NewBroadcast(8)
+	readers := []*ringbuffer.BroadcastReader{b.NewReader(), b.NewReader(), b.NewReader()}
+
+	_, err := b.Write([]byte("abcdef"))
+	if err != nil {
+		t.Errorf("Didn't expect error when writing to broadcast: %+v\n", err)
+	}
+
+	readBuf := make([]byte, 8)
+	for i, rd := range readers {
+		n, _ := rd.Read(readBuf)
+		if string(readBuf[:n]) != "abcdef" {
+			t.Errorf("Expected reader %d to read abcdef, got %s", i, readBuf[:n])
+		}
+	}
+}
+
+func TestBroadcastGatedBySlowestReader(t *testing.T) {
+	b := ringbuffer.NewBroadcast(8)
+	fast := b.NewReader()
+	slow := b.NewReader()
+
+	b.Write([]byte("abcdef"))
+	fast.Read(make([]byte, 8))
+
+	n, err := b.Write([]byte("ghijk"))
+	if n != 2 || !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected the slow reader to limit the write to 2 bytes, got %d, %+v", n, err)
+	}
+
+	readBuf := make([]byte, 16)
+	n, _ = slow.Read(readBuf)
+	if string(readBuf[:n]) != "abcdefgh" {
+		t.Errorf("Expected slow reader to read abcdefgh, got %s", readBuf[:n])
+	}
+
+	// closing the slow reader lets the writer go at the fast reader's pace
+	slow.Close()
+	fast.Read(readBuf)
+	n, err = b.Write([]byte("01234567"))
+	if n != 8 || err != nil {
+		t.Errorf("Expected a closed reader to not gate the writer, got %d, %+v", n, err)
+	}
+	if b.Readers() != 1 {
+		t.Errorf("Expected 1 registered reader, got %d", b.Readers())
+	}
+}
+
+func TestBroadcastBadCapacityPanics(t *testing.T) {
+	constructors := map[string]func(int) *ringbuffer.Broadcast{
+		"NewBroadcast":        ringbuffer.NewBroadcast,
+		"NewLappingBroadcast": ringbuffer.NewLappingBroadcast,
+	}
+	for name, newBroadcast := range constructors {
+		for _, capacity := range []int{0, -1} {
+			func() {
+				defer func() {
+					if recover() != ringbuffer.ErrBadCapacity {
+						t.Errorf("Expected %s(%d) to panic with ErrBadCapacity", name, capacity)
+					}
+				}()
+				newBroadcast(capacity)
+			}()
+		}
+	}
+}
+
+func TestBroadcastLapping(t *testing.T) {
+	b := ringbuffer.NewLappingBroadcast(8)
+	rd := b.NewReader()
+
+	b.Write([]byte("abcdef"))
+	n, err := b.Write([]byte("ghijk"))
+	if n != 5 || err != nil {
+		t.Errorf("Expected lapping writer to never fail, got %d, %+v", n, err)
+	}
+
+	readBuf := make([]byte, 16)
+	n, err = rd.Read(readBuf)
+	lapped, ok := err.(*ringbuffer.LappedError)
+	if n != 0 || !ok || lapped.Missed != 3 {
+		t.Fatalf("Expected reader to be told it missed 3 bytes, got %d, %+v", n, err)
+	}
+
+	n, _ = rd.Read(readBuf)
+	if string(readBuf[:n]) != "defghijk" {
+		t.Errorf("Expected lapped reader to continue from the oldest data, got %s", readBuf[:n])
+	}
+
+	// a write larger than capacity laps everyone
+	b.Write([]byte("0123456789"))
+	_, err = rd.Read(readBuf)
+	if lapped, ok := err.(*ringbuffer.LappedError); !ok || lapped.Missed != 2 {
+		t.Fatalf("Expected reader to be told it missed 2 bytes, got %+v", err)
+	}
+	n, _ = rd.Read(readBuf)
+	if string(readBuf[:n]) != "23456789" {
+		t.Errorf("Expected reader to read the last 8 bytes, got %s", readBuf[:n])
+	}
+}
+
+func TestBroadcastClose(t *testing.T) {
+	b := ringbuffer.NewBroadcast(8)
+	rd := b.NewReader()
+
+	b.Write([]byte("abc"))
+	b.Close()
+
+	if _, err := b.Write([]byte("def")); err != ringbuffer.ErrClosed {
+		t.Errorf("Expected ErrClosed when writing to closed broadcast, got %+v", err)
+	}
+
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, rd); err != nil || out.String() != "abc" {
+		t.Errorf("Expected to read abc then io.EOF, got %s, %+v", out.String(), err)
+	}
+}
+
+// TestBroadcastConcurrent fans one stream out to several reader goroutines,
+// and checks each of them reads all of it. Run it with -race.
+func TestBroadcastConcurrent(t *testing.T) {
+	b := ringbuffer.NewBroadcast(64)
+
+	testData := make([]byte, 1<<16)
+	for i := range testData {
+		testData[i] = byte(i * 7)
+	}
+
+	const numReaders = 3
+	outs := make([]bytes.Buffer, numReaders)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numReaders; i++ {
+		wg.Add(1)
+		go func(rd *ringbuffer.BroadcastReader, out *bytes.Buffer) {
+			defer wg.Done()
+			readBuf := make([]byte, 13)
+			for {
+				n, err := rd.Read(readBuf)
+				out.Write(readBuf[:n])
+				if err == io.EOF {
+					return
+				}
+				if n == 0 {
+					runtime.Gosched()
+				}
+			}
+		}(b.NewReader(), &outs[i])
+	}
+
+	remaining := testData
+	for len(remaining) > 0 {
+		chunk := remaining
+		if len(chunk) > 100 {
+			chunk = chunk[:100]
+		}
+		n, _ := b.Write(chunk)
+		remaining = remaining[n:]
+		if n == 0 {
+			runtime.Gosched()
+		}
+	}
+	b.Close()
+	wg.Wait()
+
+	for i := range outs {
+		if !bytes.Equal(outs[i].Bytes(), testData) {
+			t.Errorf("expected reader %d to read same as what i wrote\n", i)
+		}
+	}
+}
+
+func TestBroadcastLappingConcurrent(t *testing.T) {
+	b := ringbuffer.NewLappingBroadcast(64)
+	rd := b.NewReader()
+
+	go func() {
+		writeBuf := make([]byte, 5)
+		next := byte(0)
+		for i := 0; i < 100000; i++ {
+			for j := range writeBuf {
+				writeBuf[j] = next
+				next++
+			}
+			b.Write(writeBuf)
+		}
+		b.Close()
+	}()
+
+	// bytes are always consecutive within a read, laps only cause gaps
+	// between reads
+	readBuf := make([]byte, 7)
+	for {
+		n, err := rd.Read(readBuf)
+		if err == io.EOF {
+			break
+		}
+		if _, ok := err.(*ringbuffer.LappedError); err != nil && err != ringbuffer.ErrEmpty && !ok {
+			t.Fatalf("Didn't expect error reading from broadcast: %+v", err)
+		}
+		for i := 1; i < n; i++ {
+			if readBuf[i] != readBuf[i-1]+1 {
+				t.Fatalf("Read torn data %v", readBuf[:n])
+			}
+		}
+	}
+}
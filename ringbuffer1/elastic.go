@@ -0,0 +1,187 @@
+package ringbuffer
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+var _ io.ReadWriteCloser = (*ElasticRingbuffer)(nil)
+
+// An ElasticRingbuffer is a ringbuffer that grows its storage when a write
+// doesn't fit, instead of returning ErrFull, up to a maximum capacity. Once
+// it has grown, it shrinks back towards its initial capacity when it stays
+// at most a quarter full for a while, whether or not it is being used.
+//
+// It is a Ringbuffer resized under a mutex, so unlike Ringbuffer it is safe
+// for any number of readers and writers, but it isn't lock-free.
+type ElasticRingbuffer struct {
+	mu          sync.Mutex
+	r           Ringbuffer
+	minCapacity int
+	maxCapacity int
+	shrinkAfter time.Duration
+	lowSince    time.Time   // when utilization last dropped to a quarter
+	shrinkTimer *time.Timer // armed while lowSince is set
+}
+
+// NewElasticRingbuffer creates a ringbuffer with the specified initial
+// capacity, that doubles its storage as needed, up to ceiling bytes.
+// After being at most a quarter full for shrinkAfter, it shrinks its storage
+// to the smallest power-of-two fraction that still holds its contents, but
+// never below the initial capacity.
+//
+// It returns ErrBadCapacity if either capacity is invalid for NewRingbuffer,
+// or the ceiling is smaller than the initial capacity.
+func NewElasticRingbuffer(capacity, ceiling int, shrinkAfter time.Duration) (*ElasticRingbuffer, error) {
+	if capacity <= 0 || ceiling < capacity || uint64(ceiling) > maxCapacity {
+		return nil, ErrBadCapacity
+	}
+
+	return &ElasticRingbuffer{
+		r:           NewRingbuffer(capacity),
+		minCapacity: capacity,
+		maxCapacity: ceiling,
+		shrinkAfter: shrinkAfter,
+	}, nil
+}
+
+// grow doubles the storage until there is room for n more bytes, or it
+// reaches the maximum capacity.
+func (e *ElasticRingbuffer) grow(n int) {
+	needed := e.r.Size() + n
+	capacity := e.r.Capacity()
+	if needed <= capacity || capacity == e.maxCapacity {
+		return
+	}
+
+	for capacity < needed && capacity < e.maxCapacity {
+		capacity *= 2
+	}
+	if capacity > e.maxCapacity {
+		capacity = e.maxCapacity
+	}
+	e.r.Resize(capacity)
+	e.disarmShrink()
+}
+
+// watchUtilization arms the shrink timer when the storage drops to at most a
+// quarter full, and disarms it when it fills up again. It is called after
+// every Read and Write.
+func (e *ElasticRingbuffer) watchUtilization() {
+	capacity := e.r.Capacity()
+	if capacity == e.minCapacity || e.r.Size() > capacity/4 {
+		e.disarmShrink()
+		return
+	}
+	if !e.lowSince.IsZero() {
+		return
+	}
+
+	e.lowSince = time.Now()
+	if e.shrinkTimer == nil {
+		e.shrinkTimer = time.AfterFunc(e.shrinkAfter, e.shrinkIdle)
+	} else {
+		e.shrinkTimer.Reset(e.shrinkAfter)
+	}
+}
+
+func (e *ElasticRingbuffer) disarmShrink() {
+	if e.lowSince.IsZero() {
+		return
+	}
+	e.lowSince = time.Time{}
+	e.shrinkTimer.Stop()
+}
+
+// shrinkIdle runs when the shrink timer fires, so that a ringbuffer that is
+// no longer being read or written still gives back its storage.
+func (e *ElasticRingbuffer) shrinkIdle() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// the timer may have been disarmed, or rearmed, while this call was
+	// waiting for the lock
+	if e.lowSince.IsZero() || time.Since(e.lowSince) < e.shrinkAfter {
+		return
+	}
+	e.lowSince = time.Time{}
+	e.shrink()
+}
+
+// shrink steps the storage straight down to the smallest power-of-two
+// fraction of the current capacity that still holds the contents, but never
+// below the initial capacity.
+func (e *ElasticRingbuffer) shrink() {
+	size := e.r.Size()
+	capacity := e.r.Capacity()
+	for capacity > e.minCapacity && capacity/2 >= size {
+		capacity /= 2
+	}
+	if capacity < e.minCapacity {
+		capacity = e.minCapacity
+	}
+	if capacity != e.r.Capacity() {
+		e.r.Resize(capacity)
+	}
+}
+
+// Write copies all of buf into the ringbuffer, growing it if needed. If it
+// would have to grow past its maximum capacity, it stores what fits and
+// returns a *CapacityError, like Ringbuffer.Write.
+func (e *ElasticRingbuffer) Write(buf []byte) (n int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.r.isClosed() {
+		e.grow(len(buf))
+	}
+	n, err = e.r.Write(buf)
+	e.watchUtilization()
+	return n, err
+}
+
+// Read fills buf with as much data as can fit, like Ringbuffer.Read.
+func (e *ElasticRingbuffer) Read(buf []byte) (n int, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	n, err = e.r.Read(buf)
+	e.watchUtilization()
+	return n, err
+}
+
+// Size returns the number of bytes written and not yet read.
+func (e *ElasticRingbuffer) Size() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.r.Size()
+}
+
+// Empty returns true if the ringbuffer is empty, false otherwise.
+func (e *ElasticRingbuffer) Empty() bool {
+	return e.Size() == 0
+}
+
+// Capacity returns the current capacity of the storage, which changes as
+// the ringbuffer grows and shrinks.
+func (e *ElasticRingbuffer) Capacity() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.r.Capacity()
+}
+
+// MaxCapacity returns the capacity the ringbuffer won't grow past.
+func (e *ElasticRingbuffer) MaxCapacity() int {
+	return e.maxCapacity
+}
+
+// Close marks the ringbuffer as closed, like Ringbuffer.Close, and stops
+// the shrink timer.
+func (e *ElasticRingbuffer) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.disarmShrink()
+	return e.r.Close()
+}
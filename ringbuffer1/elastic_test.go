@@ -0,0 +1,100 @@
+package ringbuffer_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestElasticRingbufferGrow(t *testing.T) {
+	ringbuf, err := ringbuffer.NewElasticRingbuffer(4, 24, time.Hour)
+	if err != nil {
+		t.Fatalf("Didn't expect error creating elastic ringbuf: %+v", err)
+	}
+
+	ringbuf.Write([]byte("abc"))
+	if n, err := ringbuf.Write([]byte("defghi")); n != 6 || err != nil {
+		t.Errorf("Expected the write to grow the ringbuf, got %d, %+v", n, err)
+	}
+	if ringbuf.Capacity() != 16 {
+		t.Errorf("Expected capacity to double twice to 16, got %d", ringbuf.Capacity())
+	}
+
+	// the last doubling is capped at the ceiling
+	n, err := ringbuf.Write([]byte("jklmnopqrstuvwxyz"))
+	if n != 15 || !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected a short write at the ceiling, got %d, %+v", n, err)
+	}
+	if ringbuf.Capacity() != 24 {
+		t.Errorf("Expected capacity to stop at 24, got %d", ringbuf.Capacity())
+	}
+
+	buf := make([]byte, 24)
+	ringbuf.Read(buf)
+	if string(buf) != "abcdefghijklmnopqrstuvwx" {
+		t.Errorf("Expected to read everything in order, got %s", buf)
+	}
+}
+
+func TestElasticRingbufferShrink(t *testing.T) {
+	ringbuf, _ := ringbuffer.NewElasticRingbuffer(4, 64, 10*time.Millisecond)
+
+	data := bytes.Repeat([]byte("x"), 64)
+	ringbuf.Write(data)
+	if ringbuf.Capacity() != 64 {
+		t.Fatalf("Expected to grow to 64, got %d", ringbuf.Capacity())
+	}
+	ringbuf.Read(data)
+
+	// the ringbuffer shrinks on its own while idle, straight down to the
+	// initial capacity
+	deadline := time.Now().Add(5 * time.Second)
+	for ringbuf.Capacity() > 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ringbuf.Capacity() != 4 {
+		t.Errorf("Expected to shrink back to 4, got %d", ringbuf.Capacity())
+	}
+
+	ringbuf.Write([]byte("abc"))
+	buf := make([]byte, 3)
+	ringbuf.Read(buf)
+	if string(buf) != "abc" {
+		t.Errorf("Expected to read abc after shrinking, got %s", buf)
+	}
+}
+
+func TestElasticRingbufferShrinkKeepsContents(t *testing.T) {
+	ringbuf, _ := ringbuffer.NewElasticRingbuffer(4, 64, 10*time.Millisecond)
+	defer ringbuf.Close()
+
+	data := bytes.Repeat([]byte("x"), 59)
+	ringbuf.Write(append(data, "hello"...))
+	ringbuf.Read(data)
+
+	// 5 bytes are left, the smallest fraction of 64 that holds them is 8
+	deadline := time.Now().Add(5 * time.Second)
+	for ringbuf.Capacity() == 64 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ringbuf.Capacity() != 8 {
+		t.Errorf("Expected to shrink straight to 8, got %d", ringbuf.Capacity())
+	}
+
+	buf := make([]byte, 8)
+	if n, _ := ringbuf.Read(buf); string(buf[:n]) != "hello" {
+		t.Errorf("Expected to read hello after shrinking, got %s", buf[:n])
+	}
+}
+
+func TestElasticRingbufferBadCapacity(t *testing.T) {
+	if _, err := ringbuffer.NewElasticRingbuffer(8, 4, time.Second); err != ringbuffer.ErrBadCapacity {
+		t.Errorf("Expected ErrBadCapacity for a ceiling below the capacity, got %+v", err)
+	}
+	if _, err := ringbuffer.NewElasticRingbuffer(0, 4, time.Second); err != ringbuffer.ErrBadCapacity {
+		t.Errorf("Expected ErrBadCapacity for a zero capacity, got %+v", err)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/sevagh/ringworm/ringbuffer1
 
-go 1.13
+go 1.23
 
 require (
 	github.com/bmkessler/fastdiv v0.0.0-20190227075523-41d5178f2044
@@ -0,0 +1,70 @@
+package ringbuffer
+
+import "iter"
+
+// byteAt returns the byte i bytes past the read pointer read, which was
+// loaded when iteration started. It returns false if read has moved since,
+// since the byte may have been overwritten.
+func (r *Ringbuffer) byteAt(read uint32, i int) (byte, bool) {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	if r.readPtr() != read {
+		return 0, false
+	}
+	return r.buf[r.mask(read+uint32(i))], true
+}
+
+// All returns an iterator over the bytes in the ringbuffer, from oldest to
+// newest, paired with their offset from the read pointer. Nothing is
+// consumed.
+//
+// It walks the data that was in the ringbuffer when iteration started; bytes
+// written after that aren't included. If the read pointer moves during
+// iteration, because the consumer read from the ringbuffer in the loop body
+// or the producer evicted data in overwrite mode, the iteration stops before
+// yielding a byte that may have been overwritten. Like Peek, only the
+// consumer should iterate.
+func (r *Ringbuffer) All() iter.Seq2[int, byte] {
+	return func(yield func(int, byte) bool) {
+		read := r.readPtr()
+		size := int(r.distance(read, r.writePtr()))
+		for i := 0; i < size; i++ {
+			b, ok := r.byteAt(read, i)
+			if !ok || !yield(i, b) {
+				return
+			}
+		}
+	}
+}
+
+// Backward is like All, but iterates from newest to oldest.
+func (r *Ringbuffer) Backward() iter.Seq2[int, byte] {
+	return func(yield func(int, byte) bool) {
+		read := r.readPtr()
+		size := int(r.distance(read, r.writePtr()))
+		for i := size - 1; i >= 0; i-- {
+			b, ok := r.byteAt(read, i)
+			if !ok || !yield(i, b) {
+				return
+			}
+		}
+	}
+}
+
+// Draining returns an iterator that reads the ringbuffer one byte at a time,
+// consuming each byte before it is yielded. It keeps going until the
+// ringbuffer is empty, so bytes written during iteration are drained too.
+// Breaking out of the loop leaves the remaining bytes in the ringbuffer.
+//
+// It is a consumer operation, like Read.
+func (r *Ringbuffer) Draining() iter.Seq[byte] {
+	return func(yield func(byte) bool) {
+		var b [1]byte
+		for {
+			if n, _ := r.Read(b[:]); n == 0 || !yield(b[0]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package ringbuffer_test
+
+import (
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestRingbufferAll(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(8, "abcdef")
+
+	var got []byte
+	for i, b := range ringbuf.All() {
+		if i != len(got) {
+			t.Errorf("Expected offset %d, got %d", len(got), i)
+		}
+		got = append(got, b)
+	}
+	if string(got) != "abcdef" {
+		t.Errorf("Expected to walk abcdef across the wraparound, got %s", got)
+	}
+
+	got = got[:0]
+	for i, b := range ringbuf.Backward() {
+		if i != 5-len(got) {
+			t.Errorf("Expected offset %d, got %d", 5-len(got), i)
+		}
+		got = append(got, b)
+	}
+	if string(got) != "fedcba" {
+		t.Errorf("Expected to walk fedcba backwards, got %s", got)
+	}
+
+	if ringbuf.Size() != 6 {
+		t.Errorf("Expected iterating not to consume, got size %d", ringbuf.Size())
+	}
+}
+
+func TestRingbufferAllModified(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+	ringbuf.Write([]byte("abcd"))
+
+	// writes during iteration aren't seen, reads stop it
+	var got []byte
+	for _, b := range ringbuf.All() {
+		got = append(got, b)
+		ringbuf.Write([]byte("x"))
+	}
+	if string(got) != "abcd" {
+		t.Errorf("Expected to walk only abcd, got %s", got)
+	}
+
+	got = got[:0]
+	for _, b := range ringbuf.All() {
+		got = append(got, b)
+		ringbuf.Read(make([]byte, 1))
+	}
+	if string(got) != "a" {
+		t.Errorf("Expected a read to stop the iteration, got %s", got)
+	}
+}
+
+func TestRingbufferDraining(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(4)
+	ringbuf.Write([]byte("abc"))
+
+	var got []byte
+	for b := range ringbuf.Draining() {
+		got = append(got, b)
+		if b == 'a' {
+			ringbuf.Write([]byte("de"))
+		}
+		if b == 'd' {
+			break
+		}
+	}
+	if string(got) != "abcd" {
+		t.Errorf("Expected to drain abcd, got %s", got)
+	}
+	if ret := string(ringbuf.Drain()); ret != "e" {
+		t.Errorf("Expected e to be left after breaking, got %s", ret)
+	}
+}
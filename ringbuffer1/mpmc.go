@@ -0,0 +1,190 @@
+package ringbuffer
+
+import (
+	"io"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/bmkessler/fastdiv"
+)
+
+var _ io.ReadWriteCloser = (*MPMCRingbuffer)(nil)
+
+// An MPMCRingbuffer is a ringbuffer of bytes that any number of goroutines
+// can write to and read from concurrently.
+//
+// It uses CAS reservations on free-running uint64 cursors. Each side has a
+// reserve cursor, which a writer (or reader) advances with compare-and-swap
+// to claim a region of storage, and a commit cursor, which publishes the
+// region to the other side once it has been copied. Regions are committed in
+// the order they were reserved, so a goroutine that finishes copying early
+// yields until the ones ahead of it have committed. This means the ring is
+// lock-free for claiming space, but a goroutine descheduled between reserving
+// and committing holds up the goroutines behind it on the same side.
+//
+// Each Write is stored contiguously and is never interleaved with another
+// Write. Reads may split the data of a single Write between consumers, so
+// callers that need message boundaries should read and write in fixed-size
+// records.
+type MPMCRingbuffer struct {
+	// uint64s first, for 64-bit atomic alignment on 32-bit platforms
+	writeReserve uint64
+	writeCommit  uint64
+	readReserve  uint64
+	readCommit   uint64
+	closed       uint32
+	buf          []byte
+	n            fastdiv.Uint64
+}
+
+// NewMPMCRingbuffer creates a multi-producer multi-consumer ringbuffer with
+// the specified capacity. It panics with ErrBadCapacity if the capacity isn't
+// positive.
+func NewMPMCRingbuffer(capacity int) MPMCRingbuffer {
+	if capacity <= 0 {
+		panic(ErrBadCapacity)
+	}
+
+	buf := make([]byte, capacity)
+	return MPMCRingbuffer{
+		buf: buf,
+		n:   fastdiv.NewUint64(uint64(len(buf))),
+	}
+}
+
+// regions returns the storage for count bytes starting at the free-running
+// cursor ptr.
+func (r *MPMCRingbuffer) regions(ptr, count uint64) (first, second []byte) {
+	return split(r.buf, int(r.n.Mod(ptr)), int(count))
+}
+
+// Size returns the number of bytes that have been committed by writers and
+// not yet released by readers.
+func (r *MPMCRingbuffer) Size() int {
+	// load readCommit first, it can't overtake a later load of writeCommit,
+	// and retry until it didn't move in between, so the pair is consistent
+	for {
+		read := atomic.LoadUint64(&r.readCommit)
+		write := atomic.LoadUint64(&r.writeCommit)
+		if atomic.LoadUint64(&r.readCommit) == read {
+			return int(write - read)
+		}
+	}
+}
+
+// Empty returns true if the ringbuffer is empty, false otherwise.
+func (r *MPMCRingbuffer) Empty() bool {
+	return r.Size() == 0
+}
+
+// Full returns true if the ringbuffer is full, false otherwise.
+func (r *MPMCRingbuffer) Full() bool {
+	return r.Size() == r.Capacity()
+}
+
+// Capacity returns the capacity of the underlying []byte buf.
+func (r *MPMCRingbuffer) Capacity() int {
+	return len(r.buf)
+}
+
+// Write copies all the bytes in the provided []byte slice into the
+// ringbuffer. Unlike Ringbuffer.Write, it never stores a partial write: if
+// there isn't enough space for the entire write, nothing is written and a
+// *CapacityError is returned, so that the bytes of concurrent writes never
+// interleave. Writing to a closed ringbuffer returns ErrClosed.
+func (r *MPMCRingbuffer) Write(buf []byte) (n int, err error) {
+	if atomic.LoadUint32(&r.closed) == 1 {
+		return 0, ErrClosed
+	}
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	desiredWrite := uint64(len(buf))
+	capacity := uint64(len(r.buf))
+
+	var write uint64
+	for {
+		// load readCommit first, so it can't be ahead of write
+		read := atomic.LoadUint64(&r.readCommit)
+		write = atomic.LoadUint64(&r.writeReserve)
+		if write-read+desiredWrite > capacity {
+			// readers may have released space and other writers reserved
+			// it between the two loads, which overstates what is used
+			if atomic.LoadUint64(&r.readCommit) != read {
+				continue
+			}
+			available := 0
+			if write-read < capacity {
+				available = int(capacity - (write - read))
+			}
+			return 0, &CapacityError{Requested: len(buf), Available: available}
+		}
+		if atomic.CompareAndSwapUint64(&r.writeReserve, write, write+desiredWrite) {
+			break
+		}
+	}
+
+	first, second := r.regions(write, desiredWrite)
+	copy(first, buf)
+	copy(second, buf[len(first):])
+
+	// publish in reservation order
+	for atomic.LoadUint64(&r.writeCommit) != write {
+		runtime.Gosched()
+	}
+	atomic.StoreUint64(&r.writeCommit, write+desiredWrite)
+
+	return len(buf), nil
+}
+
+// Read fills the provided []byte slice with as much data as can fit. Read
+// never blocks. If the ringbuffer is empty it returns 0, ErrEmpty, unless the
+// ringbuffer has been closed, in which case it returns 0, io.EOF.
+func (r *MPMCRingbuffer) Read(buf []byte) (n int, err error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+
+	var read, readCount uint64
+	for {
+		closed := atomic.LoadUint32(&r.closed) == 1
+		// load readReserve first, so it can't be ahead of write
+		read = atomic.LoadUint64(&r.readReserve)
+		write := atomic.LoadUint64(&r.writeCommit)
+
+		readCount = write - read
+		if readCount == 0 {
+			if closed {
+				return 0, io.EOF
+			}
+			return 0, ErrEmpty
+		}
+		if readCount > uint64(len(buf)) {
+			readCount = uint64(len(buf))
+		}
+		if atomic.CompareAndSwapUint64(&r.readReserve, read, read+readCount) {
+			break
+		}
+	}
+
+	first, second := r.regions(read, readCount)
+	copy(buf, first)
+	copy(buf[len(first):], second)
+
+	// release in reservation order
+	for atomic.LoadUint64(&r.readCommit) != read {
+		runtime.Gosched()
+	}
+	atomic.StoreUint64(&r.readCommit, read+readCount)
+
+	return int(readCount), nil
+}
+
+// Close marks the ringbuffer as closed. Subsequent writes return ErrClosed,
+// and once the remaining data has been read, Read returns io.EOF. Close
+// should be called after all writers have returned, otherwise readers may
+// report io.EOF before an in-flight write is committed.
+func (r *MPMCRingbuffer) Close() error {
+	atomic.StoreUint32(&r.closed, 1)
+	return nil
+}
@@ -0,0 +1,206 @@
+package ringbuffer_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestMPMCRingbufferWrite(t *testing.T) {
+	ringbuf := ringbuffer.NewMPMCRingbuffer(8)
+
+	_, err := ringbuf.Write([]byte("abcdef"))
+	if err != nil {
+		t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
+	}
+
+	readBuf := make([]byte, 4)
+	n, _ := ringbuf.Read(readBuf)
+	if string(readBuf[:n]) != "abcd" {
+		t.Errorf("Expected to read abcd, got %s", readBuf[:n])
+	}
+
+	// wraps around the end of storage
+	_, err = ringbuf.Write([]byte("ghijk"))
+	if err != nil {
+		t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
+	}
+	if ringbuf.Size() != 7 {
+		t.Errorf("Expected ringbuf to have 7 size, got %d", ringbuf.Size())
+	}
+
+	readBuf = make([]byte, 16)
+	n, _ = ringbuf.Read(readBuf)
+	if string(readBuf[:n]) != "efghijk" {
+		t.Errorf("Expected to read efghijk, got %s", readBuf[:n])
+	}
+}
+
+func TestMPMCRingbufferWriteTooMuch(t *testing.T) {
+	ringbuf := ringbuffer.NewMPMCRingbuffer(4)
+
+	ringbuf.Write([]byte("ab"))
+	n, err := ringbuf.Write([]byte("cde"))
+	if n != 0 || !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected write that doesn't fit to store nothing, got %d, %+v", n, err)
+	}
+	if ringbuf.Size() != 2 {
+		t.Errorf("Expected ringbuf to have 2 size, got %d", ringbuf.Size())
+	}
+}
+
+func TestMPMCRingbufferBadCapacityPanics(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() != ringbuffer.ErrBadCapacity {
+					t.Errorf("Expected NewMPMCRingbuffer(%d) to panic with ErrBadCapacity", capacity)
+				}
+			}()
+			ringbuffer.NewMPMCRingbuffer(capacity)
+		}()
+	}
+}
+
+func TestMPMCRingbufferCapacityErrorConcurrent(t *testing.T) {
+	ringbuf := ringbuffer.NewMPMCRingbuffer(8)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				_, err := ringbuf.Write([]byte("abc"))
+				var capErr *ringbuffer.CapacityError
+				if errors.As(err, &capErr) && (capErr.Available < 0 || capErr.Available >= capErr.Requested) {
+					t.Errorf("Expected 0 <= Available < Requested, got %+v", capErr)
+					return
+				}
+				runtime.Gosched()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			readBuf := make([]byte, 3)
+			for j := 0; j < 2000; j++ {
+				ringbuf.Read(readBuf)
+				runtime.Gosched()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMPMCRingbufferClose(t *testing.T) {
+	ringbuf := ringbuffer.NewMPMCRingbuffer(4)
+
+	ringbuf.Write([]byte("ab"))
+	ringbuf.Close()
+
+	if _, err := ringbuf.Write([]byte("c")); err != ringbuffer.ErrClosed {
+		t.Errorf("Expected ErrClosed when writing to closed ringbuf, got %+v", err)
+	}
+
+	readBuf := make([]byte, 4)
+	if n, err := ringbuf.Read(readBuf); n != 2 || err != nil {
+		t.Errorf("Expected to read remaining data before EOF, got %d, %+v", n, err)
+	}
+	if n, err := ringbuf.Read(readBuf); n != 0 || err != io.EOF {
+		t.Errorf("Expected io.EOF on closed empty ringbuf, got %d, %+v", n, err)
+	}
+}
+
+// TestMPMCRingbufferStress has several producers write 8-byte records of
+// (producer, sequence number) while several consumers read them, and checks
+// that every record arrives exactly once and in order per producer. Run it
+// with -race.
+func TestMPMCRingbufferStress(t *testing.T) {
+	const (
+		producers = 4
+		consumers = 4
+		records   = 10000
+		record    = 8
+	)
+
+	ringbuf := ringbuffer.NewMPMCRingbuffer(1000)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			writeBuf := make([]byte, record)
+			for seq := 0; seq < records; seq++ {
+				binary.LittleEndian.PutUint32(writeBuf, uint32(p))
+				binary.LittleEndian.PutUint32(writeBuf[4:], uint32(seq))
+				for {
+					_, err := ringbuf.Write(writeBuf)
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, ringbuffer.ErrFull) {
+						t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
+						return
+					}
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+
+	results := make([][]uint64, consumers)
+	var cwg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func(c int) {
+			defer cwg.Done()
+			readBuf := make([]byte, 5*record)
+			for {
+				n, err := ringbuf.Read(readBuf)
+				if err == io.EOF {
+					return
+				}
+				if n == 0 {
+					runtime.Gosched()
+				}
+				if n%record != 0 {
+					t.Errorf("Read split a record: %d bytes", n)
+					return
+				}
+				for i := 0; i < n; i += record {
+					results[c] = append(results[c], binary.LittleEndian.Uint64(readBuf[i:]))
+				}
+			}
+		}(c)
+	}
+
+	wg.Wait()
+	ringbuf.Close()
+	cwg.Wait()
+
+	seen := make(map[uint64]bool)
+	for c := range results {
+		last := make(map[uint32]int64)
+		for _, rec := range results[c] {
+			p, seq := uint32(rec), int64(rec>>32)
+			if prev, ok := last[p]; ok && seq <= prev {
+				t.Fatalf("consumer %d read producer %d out of order: %d after %d", c, p, seq, prev)
+			}
+			last[p] = seq
+			if seen[rec] {
+				t.Fatalf("record %d from producer %d read twice", seq, p)
+			}
+			seen[rec] = true
+		}
+	}
+
+	if len(seen) != producers*records {
+		t.Errorf("Expected %d records, got %d", producers*records, len(seen))
+	}
+}
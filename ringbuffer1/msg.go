@@ -0,0 +1,88 @@
+package ringbuffer
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// fill copies src to the start of the regions first and second, and returns
+// what is left of them.
+func fill(first, second, src []byte) ([]byte, []byte) {
+	n := copy(first, src)
+	m := copy(second, src[n:])
+	return first[n:], second[m:]
+}
+
+// WriteMsg stores msg as a single framed message: a uvarint length header
+// followed by the payload. The header and payload are published to the
+// consumer together, so ReadMsg never sees half a message, even when it
+// wraps around the end of storage.
+//
+// If the framed message doesn't fit in the free space, nothing is written and
+// a *CapacityError is returned. This is also the case in overwrite mode,
+// since evicting part of an older message would break the framing.
+//
+// Framed and unframed writes must not be mixed on the same ringbuffer.
+func (r *Ringbuffer) WriteMsg(msg []byte) error {
+	if r.isClosed() {
+		return ErrClosed
+	}
+
+	var header [binary.MaxVarintLen64]byte
+	headerLen := binary.PutUvarint(header[:], uint64(len(msg)))
+
+	total := headerLen + len(msg)
+	if available := r.Capacity() - r.Size(); total > available {
+		return &CapacityError{Requested: total, Available: available}
+	}
+
+	write := r.writePtr()
+	first, second := r.regions(write, uint32(total))
+	first, second = fill(first, second, header[:headerLen])
+	fill(first, second, msg)
+
+	r.advanceWrite(write, uint32(total))
+
+	return nil
+}
+
+// ReadMsg returns the oldest message stored with WriteMsg, and removes it
+// from the ringbuffer. If there are no messages it returns ErrEmpty, or
+// io.EOF if the ringbuffer has been closed. If the data at the read pointer
+// isn't a valid framed message it returns ErrFraming.
+func (r *Ringbuffer) ReadMsg() ([]byte, error) {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	closed := r.isClosed()
+	read := r.readPtr()
+	size := int(r.distance(read, r.writePtr()))
+	if size == 0 {
+		if closed {
+			return nil, io.EOF
+		}
+		return nil, ErrEmpty
+	}
+
+	var header [binary.MaxVarintLen64]byte
+	headerLen := len(header)
+	if size < headerLen {
+		headerLen = size
+	}
+	first, second := r.regions(read, uint32(headerLen))
+	copy(header[:], first)
+	copy(header[len(first):], second)
+
+	msgLen, headerLen := binary.Uvarint(header[:headerLen])
+	if headerLen <= 0 || msgLen > uint64(size-headerLen) {
+		return nil, ErrFraming
+	}
+
+	msg := make([]byte, msgLen)
+	first, second = r.regions(read+uint32(headerLen), uint32(msgLen))
+	copy(msg, first)
+	copy(msg[len(first):], second)
+
+	r.advanceRead(read, uint32(headerLen)+uint32(msgLen))
+	return msg, nil
+}
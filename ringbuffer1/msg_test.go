@@ -0,0 +1,87 @@
+package ringbuffer_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestRingbufferMsgRoundTrip(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+
+	testData := []string{"hello", "", "world!", "wrapped"}
+
+	for i := 0; i < len(testData); i++ {
+		err := ringbuf.WriteMsg([]byte(testData[i]))
+		if err != nil {
+			t.Errorf("Didn't expect error when writing msg to ringbuf: %+v\n", err)
+		}
+
+		if i%2 == 1 {
+			for j := i - 1; j <= i; j++ {
+				msg, err := ringbuf.ReadMsg()
+				if err != nil {
+					t.Errorf("Didn't expect error when reading msg from ringbuf: %+v\n", err)
+				}
+				if string(msg) != testData[j] {
+					t.Errorf("Expected to read one message\n\texp: %+v\n\tgot: %+v\n", testData[j], string(msg))
+				}
+			}
+		}
+	}
+
+	if _, err := ringbuf.ReadMsg(); err != ringbuffer.ErrEmpty {
+		t.Errorf("Expected ErrEmpty reading from empty ringbuf, got %+v", err)
+	}
+
+	ringbuf.Close()
+	if _, err := ringbuf.ReadMsg(); err != io.EOF {
+		t.Errorf("Expected io.EOF reading from closed ringbuf, got %+v", err)
+	}
+}
+
+func TestRingbufferMsgTooBig(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	ringbuf.WriteMsg([]byte("abc"))
+
+	// 1 byte header + 4 bytes payload doesn't fit in the remaining 4 bytes
+	if err := ringbuf.WriteMsg([]byte("defg")); !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected ErrFull when msg doesn't fit, got %+v", err)
+	}
+	if ringbuf.Size() != 4 {
+		t.Errorf("Expected a msg that doesn't fit to write nothing, got size %d", ringbuf.Size())
+	}
+}
+
+func TestRingbufferMsgFraming(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	// a header saying 100 bytes follow, without them
+	ringbuf.Write([]byte{100, 'a'})
+
+	if _, err := ringbuf.ReadMsg(); err != ringbuffer.ErrFraming {
+		t.Errorf("Expected ErrFraming for a truncated msg, got %+v", err)
+	}
+}
+
+func TestRingbufferMsgLongHeader(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(512)
+
+	// a 2 byte header, which wraps around the end of storage
+	ringbuf.Write(make([]byte, 511))
+	ringbuf.Read(make([]byte, 511))
+
+	msg := bytes.Repeat([]byte{'x'}, 200)
+	if err := ringbuf.WriteMsg(msg); err != nil {
+		t.Errorf("Didn't expect error when writing msg to ringbuf: %+v\n", err)
+	}
+
+	ret, err := ringbuf.ReadMsg()
+	if err != nil || !bytes.Equal(ret, msg) {
+		t.Errorf("Expected to read back the 200 byte msg, got %d bytes, %+v", len(ret), err)
+	}
+}
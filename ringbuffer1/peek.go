@@ -0,0 +1,99 @@
+package ringbuffer
+
+import "io"
+
+var _ io.ReaderAt = (*Ringbuffer)(nil)
+
+// peek copies the data starting off bytes past the read pointer into buf,
+// without consuming it. It returns the number of bytes copied and the size
+// of the ringbuffer at the time.
+func (r *Ringbuffer) peek(buf []byte, off int) (n, size int) {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	read := r.readPtr()
+	size = int(r.distance(read, r.writePtr()))
+	if off < size {
+		n = size - off
+		if len(buf) < n {
+			n = len(buf)
+		}
+		first, second := r.regions(read+uint32(off), uint32(n))
+		copy(buf, first)
+		copy(buf[len(first):], second)
+	}
+	return n, size
+}
+
+// Peek fills buf with as much of the oldest data as fits, like Read, but
+// without advancing the read pointer. If the ringbuffer is empty it returns
+// 0, ErrEmpty, or 0, io.EOF if it has been closed.
+func (r *Ringbuffer) Peek(buf []byte) (n int, err error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	closed := r.isClosed()
+	n, size := r.peek(buf, 0)
+	if size == 0 {
+		if closed {
+			return 0, io.EOF
+		}
+		return 0, ErrEmpty
+	}
+	return n, nil
+}
+
+// ReadAt copies the data starting off bytes past the read pointer into buf,
+// without consuming anything. The offset is relative to the oldest byte in
+// the ringbuffer, not to the start of storage, so offset 0 is what the next
+// Read returns.
+//
+// As required by io.ReaderAt, if fewer than len(buf) bytes are available at
+// off, ReadAt returns the bytes that are along with io.EOF. A negative off
+// returns ErrBadCount.
+//
+// Only the consumer should call ReadAt, since a concurrent Read would move
+// the offsets.
+func (r *Ringbuffer) ReadAt(buf []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, ErrBadCount
+	}
+	if off > int64(r.Capacity()) {
+		return 0, io.EOF
+	}
+
+	n, _ = r.peek(buf, int(off))
+	if n < len(buf) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Discard skips the next n bytes without copying them out, and returns the
+// number of bytes discarded. If there are fewer than n bytes, it discards
+// them all and returns ErrEmpty, or io.EOF if the ringbuffer has been closed.
+// A negative n returns ErrBadCount.
+func (r *Ringbuffer) Discard(n int) (discarded int, err error) {
+	if n < 0 {
+		return 0, ErrBadCount
+	}
+
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	closed := r.isClosed()
+	read := r.readPtr()
+	discarded = int(r.distance(read, r.writePtr()))
+	if n < discarded {
+		discarded = n
+	}
+	r.advanceRead(read, uint32(discarded))
+
+	if discarded < n {
+		if closed {
+			return discarded, io.EOF
+		}
+		return discarded, ErrEmpty
+	}
+	return discarded, nil
+}
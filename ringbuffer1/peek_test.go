@@ -0,0 +1,88 @@
+package ringbuffer_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestRingbufferPeek(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(8, "abcdef")
+
+	buf := make([]byte, 4)
+	n, err := ringbuf.Peek(buf)
+	if n != 4 || err != nil || string(buf) != "abcd" {
+		t.Errorf("Expected to peek abcd, got %s, %d, %+v", buf[:n], n, err)
+	}
+	if ringbuf.Size() != 6 {
+		t.Errorf("Expected Peek not to consume, got size %d", ringbuf.Size())
+	}
+
+	buf = make([]byte, 8)
+	n, _ = ringbuf.Peek(buf)
+	if string(buf[:n]) != "abcdef" {
+		t.Errorf("Expected to peek everything across the wraparound, got %s", buf[:n])
+	}
+
+	ringbuf.Discard(6)
+	if n, err := ringbuf.Peek(buf); n != 0 || err != ringbuffer.ErrEmpty {
+		t.Errorf("Expected empty peek, got %d, %+v", n, err)
+	}
+	ringbuf.Close()
+	if _, err := ringbuf.Peek(buf); err != io.EOF {
+		t.Errorf("Expected io.EOF peeking a closed empty ringbuf, got %+v", err)
+	}
+}
+
+func TestRingbufferReadAt(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(8, "xxabcdef")
+	ringbuf.Discard(2)
+
+	// a header parser looks at the length before consuming anything
+	buf := make([]byte, 3)
+	n, err := ringbuf.ReadAt(buf, 1)
+	if n != 3 || err != nil || string(buf) != "bcd" {
+		t.Errorf("Expected to read bcd at offset 1, got %s, %+v", buf[:n], err)
+	}
+
+	n, err = ringbuf.ReadAt(buf, 4)
+	if n != 2 || err != io.EOF || string(buf[:n]) != "ef" {
+		t.Errorf("Expected short read ef with io.EOF, got %s, %+v", buf[:n], err)
+	}
+
+	if n, err := ringbuf.ReadAt(buf, 6); n != 0 || err != io.EOF {
+		t.Errorf("Expected io.EOF at the end, got %d, %+v", n, err)
+	}
+	if _, err := ringbuf.ReadAt(buf, -1); err != ringbuffer.ErrBadCount {
+		t.Errorf("Expected ErrBadCount for a negative offset, got %+v", err)
+	}
+	if ringbuf.Size() != 6 {
+		t.Errorf("Expected ReadAt not to consume, got size %d", ringbuf.Size())
+	}
+}
+
+func TestRingbufferDiscard(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+	ringbuf.Write([]byte("abcdef"))
+
+	if n, err := ringbuf.Discard(4); n != 4 || err != nil {
+		t.Errorf("Expected to discard 4 bytes, got %d, %+v", n, err)
+	}
+	if ret := string(ringbuf.Drain()); ret != "ef" {
+		t.Errorf("Expected ef after discarding, got %s", ret)
+	}
+
+	ringbuf.Write([]byte("gh"))
+	if n, err := ringbuf.Discard(4); n != 2 || err != ringbuffer.ErrEmpty {
+		t.Errorf("Expected to discard the 2 bytes there were, got %d, %+v", n, err)
+	}
+	if _, err := ringbuf.Discard(-1); err != ringbuffer.ErrBadCount {
+		t.Errorf("Expected ErrBadCount for a negative count, got %+v", err)
+	}
+
+	ringbuf.Close()
+	if _, err := ringbuf.Discard(1); err != io.EOF {
+		t.Errorf("Expected io.EOF discarding from a closed empty ringbuf, got %+v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package ringbuffer
+
+import "sync/atomic"
+
+// Clear discards all of the data in the ringbuffer, as if it had been read.
+// It is a consumer operation, and is safe to call while the producer is
+// writing; anything written after the data was discarded is kept.
+func (r *Ringbuffer) Clear() {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	read := r.readPtr()
+	r.advanceRead(read, r.distance(read, r.writePtr()))
+}
+
+// Reset empties the ringbuffer and reopens it if it was closed, returning it
+// to the state it was created in while keeping its storage. Any outstanding
+// reservation is dropped.
+//
+// Reset is not safe to call concurrently with any other method, including
+// calls to ReadContext and WriteContext blocked in other goroutines. It is
+// meant for reusing a ringbuffer once both sides are done with it, e.g. from
+// a sync.Pool.
+func (r *Ringbuffer) Reset() {
+	atomic.StoreUint32(&r.read, 0)
+	atomic.StoreUint32(&r.write, 0)
+	r.cachedRead = 0
+	r.cachedWrite = 0
+	r.reserved = 0
+
+	if atomic.LoadUint32(&r.closed) == 1 {
+		r.done = make(chan struct{})
+		atomic.StoreUint32(&r.closed, 0)
+	}
+
+	// drop stale wakeups meant for the previous users
+	select {
+	case <-r.readable:
+	default:
+	}
+	select {
+	case <-r.writable:
+	default:
+	}
+}
+
+// Resize replaces the ringbuffer's storage with a new one of capacity bytes,
+// and moves the data over, in order. The closed state and overwrite mode are
+// kept, any outstanding reservation is dropped, and the divisors used to
+// index storage are recomputed for the new capacity.
+//
+// It returns ErrBadCapacity, and leaves the ringbuffer as it was, if the new
+// capacity isn't valid for NewRingbuffer or is smaller than Size.
+//
+// Neither the producer nor the consumer may use the ringbuffer while Resize
+// runs: it must be called from the only goroutine accessing it, or with both
+// sides otherwise stopped. Third-party calls to Size, Empty, Full and
+// Capacity are not safe either. ReadContext and WriteContext calls blocked in
+// other goroutines are allowed, and are woken up afterwards to recheck the
+// ringbuffer.
+func (r *Ringbuffer) Resize(capacity int) error {
+	size := r.Size()
+	if capacity <= 0 || uint64(capacity) > maxCapacity || capacity < size {
+		return ErrBadCapacity
+	}
+
+	buf := make([]byte, capacity)
+	first, second := r.PeekRegions()
+	copy(buf, first)
+	copy(buf[len(first):], second)
+
+	r.setStorage(buf)
+	atomic.StoreUint32(&r.read, 0)
+	atomic.StoreUint32(&r.write, r.mask2(uint32(size)))
+	r.cachedRead = 0
+	r.cachedWrite = r.writePtr()
+	r.reserved = 0
+
+	if atomic.LoadUint32(&r.readWaiting) == 1 {
+		wake(r.readable)
+	}
+	if atomic.LoadUint32(&r.writeWaiting) == 1 {
+		wake(r.writable)
+	}
+	return nil
+}
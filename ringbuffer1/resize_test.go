@@ -0,0 +1,81 @@
+package ringbuffer_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestRingbufferClear(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(8, "abcdef")
+
+	ringbuf.Clear()
+	if !ringbuf.Empty() {
+		t.Errorf("Expected Clear to empty the ringbuf, got size %d", ringbuf.Size())
+	}
+
+	ringbuf.Write([]byte("gh"))
+	if ret := string(ringbuf.Drain()); ret != "gh" {
+		t.Errorf("Expected to read gh after Clear, got %s", ret)
+	}
+}
+
+func TestRingbufferReset(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(8, "abcdef")
+	ringbuf.Reserve(2)
+	ringbuf.Close()
+
+	ringbuf.Reset()
+	if !ringbuf.Empty() {
+		t.Errorf("Expected Reset to empty the ringbuf, got size %d", ringbuf.Size())
+	}
+	if err := ringbuf.Commit(1); err != ringbuffer.ErrBadCount {
+		t.Errorf("Expected Reset to drop the reservation, got %+v", err)
+	}
+
+	if n, err := ringbuf.Write([]byte("abcdefgh")); n != 8 || err != nil {
+		t.Errorf("Expected a reset ringbuf to be open and empty, got %d, %+v", n, err)
+	}
+	if ret := string(ringbuf.Drain()); ret != "abcdefgh" {
+		t.Errorf("Expected to read abcdefgh, got %s", ret)
+	}
+
+	ringbuf.Close()
+	if _, err := ringbuf.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected Close to work again after Reset, got %+v", err)
+	}
+}
+
+func TestRingbufferResize(t *testing.T) {
+	// between pow2 and non-pow2 capacities, both ways
+	capacities := []int{8, 13, 32, 7, 6}
+
+	ringbuf := newWrappedRingbuffer(8, "abcdef")
+	for _, capacity := range capacities {
+		if err := ringbuf.Resize(capacity); err != nil {
+			t.Errorf("Didn't expect error resizing to %d: %+v", capacity, err)
+		}
+		if ringbuf.Capacity() != capacity || ringbuf.Size() != 6 {
+			t.Errorf("Expected capacity %d with 6 bytes, got %d, %d", capacity, ringbuf.Capacity(), ringbuf.Size())
+		}
+
+		// wrap around the new storage and check the order was kept
+		buf := make([]byte, 6)
+		ringbuf.Read(buf)
+		if string(buf) != "abcdef" {
+			t.Errorf("Expected abcdef after resizing to %d, got %s", capacity, buf)
+		}
+		ringbuf.Write(buf)
+	}
+
+	if err := ringbuf.Resize(5); err != ringbuffer.ErrBadCapacity {
+		t.Errorf("Expected ErrBadCapacity shrinking below Size, got %+v", err)
+	}
+	if err := ringbuf.Resize(0); err != ringbuffer.ErrBadCapacity {
+		t.Errorf("Expected ErrBadCapacity for a zero capacity, got %+v", err)
+	}
+	if ret := string(ringbuf.Drain()); ret != "abcdef" {
+		t.Errorf("Expected a failed Resize to keep the data, got %s", ret)
+	}
+}
@@ -5,39 +5,178 @@ The indexing strategy is taken from the conversations here:
 https://www.snellman.net/blog/archive/2016-12-13-ring-buffers/
 
 The read and write pointers are uint32s that are stored modulo 2*capacity,
-and are moduloed with capacity to index the underlying []byte storage.
+and are moduloed with capacity to index the underlying []byte storage. For
+capacities that don't fit in uint32 pointers, use Ringbuffer64.
+
+If the capacity is a power of two, the pointers instead run freely and wrap
+around at 2^32, which is a multiple of the capacity, and are masked with
+capacity-1 to index the storage. This skips the fastdiv modulo entirely.
 
 Here are some of the characteristics:
-- SPSC (single producer single consumer)
+- SPSC (single producer single consumer), see MPMCRingbuffer and Broadcast
 - Lock-free using sync.atomic
-- Fixed size, no growing
+- Fixed size, see Resize, or ElasticRingbuffer to grow on demand
+- Optional overwrite-oldest mode with NewOverwritingRingbuffer
+- Implements io.Reader, io.Writer and io.Closer
+- Optional message framing with WriteMsg and ReadMsg
+- Delimiter and line scanning with ReadSlice, ReadLine and ReadToken
+- Range-over-func iteration with All, Backward and Draining
+- Cross-process rings in shared memory with SharedRingbuffer (Linux)
+- Optional blocking reads and writes with ReadContext and WriteContext
 
 It operates on []byte, which could make it usable for a variety of different
 applications by using encoding/gob or similar.
+
+# Memory ordering
+
+Each pointer has a single writer: write belongs to the producer, and read to
+the consumer. A side publishes progress by computing its new, already reduced
+pointer locally and storing it with a single atomic store, after it has
+finished copying to or from storage. The other side atomically loads that
+pointer before touching the storage it covers. sync/atomic operations are
+sequentially consistent, so per the Go memory model the store synchronizes
+with the load that observes it: the consumer sees every byte written before
+the write pointer that it loaded, and the producer never reuses storage before
+the consumer has finished copying out of it.
+
+In overwrite mode the producer also moves read, to evict data, so every
+operation that moves read or copies out of storage holds a mutex instead, see
+NewOverwritingRingbuffer.
 */
 package ringbuffer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"sync"
 	"sync/atomic"
 
 	"github.com/bmkessler/fastdiv"
 )
 
+var (
+	// ErrFull is returned when the ringbuffer doesn't have enough free space.
+	// Writes that are cut short return a *CapacityError, which matches
+	// ErrFull with errors.Is.
+	ErrFull = errors.New("ringbuffer: full")
+
+	// ErrClosed is returned by Write after the ringbuffer has been closed.
+	ErrClosed = errors.New("ringbuffer: closed")
+
+	// ErrEmpty is returned by Read and Peek when there is nothing to read,
+	// by ReadMsg when there is no message to read, and by ReadSlice,
+	// ReadLine and ReadToken when there is no complete token. Discard
+	// returns it when there were fewer bytes than requested.
+	ErrEmpty = errors.New("ringbuffer: empty")
+
+	// ErrFraming is returned by ReadMsg when the data at the read pointer
+	// isn't a message stored with WriteMsg.
+	ErrFraming = errors.New("ringbuffer: invalid message framing")
+
+	// ErrBadCapacity is returned when creating a ringbuffer with a capacity
+	// that is zero, negative or too large for its cursors.
+	ErrBadCapacity = errors.New("ringbuffer: invalid capacity")
+
+	// ErrBadCount is returned by Commit and Consume when asked to advance
+	// past the bytes that were reserved or are available, and for negative
+	// counts and offsets.
+	ErrBadCount = errors.New("ringbuffer: count out of range")
+)
+
+// A CapacityError is returned by writes that don't fit in the ringbuffer's
+// free space. It matches ErrFull with errors.Is, and can be unpacked with
+// errors.As to find out how much space there was.
+type CapacityError struct {
+	Requested int // bytes the caller tried to store
+	Available int // free space at the time
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("ringbuffer: write of %d bytes is too big for remaining capacity %d", e.Requested, e.Available)
+}
+
+// Is reports whether target is ErrFull.
+func (e *CapacityError) Is(target error) bool {
+	return target == ErrFull
+}
+
+// maxCapacity is the largest capacity that uint32 cursors can address. A
+// cursor is below 2*capacity, and is advanced by at most capacity before
+// being reduced, so 3*capacity has to fit in a uint32.
+const maxCapacity = math.MaxUint32 / 3
+
+var _ io.ReadWriteCloser = (*Ringbuffer)(nil)
+
+// sizeAttempts bounds how many times Size retries a pair of pointers that
+// are further apart than the capacity. That only happens if read lapped back
+// to the same value between its two loads, so a few retries are plenty, and
+// anything more means the pointers are broken, which Size then reports
+// instead of spinning forever.
+const sizeAttempts = 8
+
+// cacheLineSize is the padding between the fields owned by the producer and
+// the consumer, so that they don't share a cache line.
+const cacheLineSize = 64
+
 // A Ringbuffer is a struct that allows users to store and read []byte data.
+//
+// The producer's and the consumer's fields are padded onto separate cache
+// lines. Each side also keeps a private copy of the other side's pointer, and
+// only reloads the shared one when its copy makes the ringbuffer look too
+// full (or too empty) for the operation at hand.
 type Ringbuffer struct {
-	read  uint32
-	write uint32
-	buf   []byte
-	n1    fastdiv.Uint32
-	n2    fastdiv.Uint32
+	// set at construction, or rarely written, and read by both sides
+	buf     []byte
+	n1      fastdiv.Uint32
+	n2      fastdiv.Uint32
+	closed  uint32
+	pow2    bool   // power-of-two capacities use free-running pointers
+	capMask uint32 // and capMask
+
+	// set by NewOverwritingRingbuffer, the producer may then advance read,
+	// and mu serializes the copies in and out of storage
+	overwrite bool
+	mu        *sync.Mutex
+
+	// set by a blocked reader/writer, so the other side knows to wake it
+	readWaiting  uint32
+	writeWaiting uint32
+	readable     chan struct{}
+	writable     chan struct{}
+	done         chan struct{}
+
+	_ [cacheLineSize]byte
+
+	// consumer side
+	read        uint32
+	cachedWrite uint32
+
+	_ [cacheLineSize]byte
+
+	// producer side
+	write      uint32
+	cachedRead uint32
+	reserved   int    // bytes handed out by the last Reserve
+	reservedAt uint32 // and the write pointer they start at
+
+	_ [cacheLineSize]byte
 }
 
 func (r *Ringbuffer) mask(ptr uint32) uint32 {
+	if r.pow2 {
+		return ptr & r.capMask
+	}
 	return r.n1.Mod(ptr)
 }
 
 func (r *Ringbuffer) mask2(ptr uint32) uint32 {
+	if r.pow2 {
+		// free-running, wraps around at 2^32 by itself
+		return ptr
+	}
 	return r.n2.Mod(ptr)
 }
 
@@ -49,10 +188,50 @@ func (r *Ringbuffer) readPtr() uint32 {
 	return atomic.LoadUint32(&r.read)
 }
 
+func (r *Ringbuffer) isClosed() bool {
+	return atomic.LoadUint32(&r.closed) == 1
+}
+
+// distance returns how far the write pointer is ahead of the read pointer.
+// The pointers live modulo 2*capacity, or 2^32 if the capacity is a power of
+// two, so the write pointer may have wrapped around to a smaller value than
+// the read pointer.
+func (r *Ringbuffer) distance(read, write uint32) uint32 {
+	if r.pow2 {
+		return write - read
+	}
+	if write >= read {
+		return write - read
+	}
+	return write + 2*uint32(len(r.buf)) - read
+}
+
 // Size returns the size (bytes written by the user) of the ringbuffer.
 // This is the distance between the write and read pointers.
+//
+// Size is safe to call from any goroutine. The pointers can't be loaded
+// together, so it loads read, then write, then read again, and retries until
+// read didn't move in between. The pair it returns the distance of was then
+// in place at the same time, so the result is between 0 and Capacity(), even
+// if the caller was descheduled while the producer and consumer kept going.
+// If the pointers are still further apart than the capacity after a few
+// tries, they are corrupt, and that distance is returned as it is.
 func (r *Ringbuffer) Size() int {
-	return int(r.mask2(r.writePtr() - r.readPtr()))
+	for attempt := 1; ; attempt++ {
+		read := r.readPtr()
+		write := r.writePtr()
+		if r.readPtr() != read {
+			continue
+		}
+
+		size := int(r.distance(read, write))
+		if size > len(r.buf) && attempt < sizeAttempts {
+			// read lapped all the way around to the same value while we
+			// were descheduled
+			continue
+		}
+		return size
+	}
 }
 
 // Empty returns true if the ringbuffer is empty, false otherwise.
@@ -72,84 +251,433 @@ func (r *Ringbuffer) Capacity() int {
 }
 
 // NewRingbuffer creates a ringbuffer with the specified capacity.
+//
+// It panics with ErrBadCapacity if the capacity isn't positive, or is larger
+// than uint32 pointers can address (math.MaxUint32/3 bytes). Use
+// NewRingbuffer64 for larger capacities.
 func NewRingbuffer(capacity int) Ringbuffer {
-	buf := make([]byte, capacity)
-	return Ringbuffer{
-		read:  0,
-		write: 0,
-		buf:   buf,
-		n1:    fastdiv.NewUint32(uint32(len(buf))),
-		n2:    fastdiv.NewUint32(uint32(2 * len(buf))),
+	if capacity <= 0 || uint64(capacity) > maxCapacity {
+		panic(ErrBadCapacity)
 	}
+
+	r := Ringbuffer{
+		readable: make(chan struct{}, 1),
+		writable: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	r.setStorage(make([]byte, capacity))
+	return r
+}
+
+// setStorage makes buf the ringbuffer's storage, and sets up the divisors
+// and mask for its capacity. It doesn't touch the pointers.
+func (r *Ringbuffer) setStorage(buf []byte) {
+	capacity := len(buf)
+	r.buf = buf
+	r.n1 = fastdiv.NewUint32(uint32(capacity))
+	r.n2 = fastdiv.NewUint32(uint32(2 * capacity))
+	r.pow2 = capacity&(capacity-1) == 0
+	r.capMask = uint32(capacity - 1)
 }
 
-// Write copies all the bytes in the provided []byte slice into the ringbuffer.
-// Data is copied to storage[write:], and the write pointer is advanced by n
-// bytes written.
+// NewOverwritingRingbuffer creates a ringbuffer with the specified capacity
+// that never rejects writes. When a write doesn't fit, the oldest bytes are
+// dropped to make room for it, so the ringbuffer always holds the most
+// recently written data.
 //
-// If there isn't enough space for the entire write, it returns an Error.
-func (r *Ringbuffer) Write(buf []byte) error {
-	emptyCount := r.Capacity() - r.Size()
+// In this mode the producer advances the read pointer when it evicts, and may
+// overwrite the bytes the consumer is copying out, so eviction and the copies
+// in and out of storage, along with every consumer operation that moves the
+// read pointer, are serialized by a mutex, and the ringbuffer is no longer
+// lock-free. This
+// keeps it free of data races, and usable under the race detector. The
+// slices returned by PeekRegions are the exception: the producer may
+// overwrite them at any time, so use Peek instead in this mode.
+func NewOverwritingRingbuffer(capacity int) Ringbuffer {
+	r := NewRingbuffer(capacity)
+	r.overwrite = true
+	r.mu = new(sync.Mutex)
+	return r
+}
 
-	if len(buf) > emptyCount {
-		return fmt.Errorf("write %d is too big for remaining capacity %d", len(buf), emptyCount)
+// lockStorage locks storage against the other side in overwrite mode, where
+// the producer may overwrite bytes the consumer is copying out. It does
+// nothing otherwise.
+func (r *Ringbuffer) lockStorage() {
+	if r.mu != nil {
+		r.mu.Lock()
 	}
-	desiredWrite := uint32(len(buf))
+}
 
-	capacity := uint32(len(r.buf))
-	writeIdx := r.mask(r.writePtr())
+func (r *Ringbuffer) unlockStorage() {
+	if r.mu != nil {
+		r.mu.Unlock()
+	}
+}
 
-	copy(r.buf[writeIdx:], buf)
+// wake does a non-blocking send on a notification channel. The channel has
+// room for one pending wakeup, which is all a single waiter needs.
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
 
-	if writeIdx+desiredWrite > capacity {
+// regions returns the one or two contiguous slices of storage that hold
+// count bytes starting at the (unmasked) pointer ptr. The second slice is
+// only non-empty if the bytes wrap around the end of storage.
+func (r *Ringbuffer) regions(ptr, count uint32) (first, second []byte) {
+	return split(r.buf, int(r.mask(ptr)), int(count))
+}
+
+// split returns the count bytes of storage starting at idx, as one slice or
+// as two if they wrap around the end of storage.
+func split(storage []byte, idx, count int) (first, second []byte) {
+	if idx+count > len(storage) {
 		// wraparound
-		remain := capacity - writeIdx
-		copy(r.buf, buf[remain:])
+		return storage[idx:], storage[:idx+count-len(storage)]
 	}
+	return storage[idx : idx+count], nil
+}
 
-	atomic.AddUint32(&r.write, desiredWrite)
-	atomic.SwapUint32(&r.write, r.mask2(r.writePtr()))
+// advanceWrite publishes n newly written bytes, starting at the write pointer
+// from, to the consumer. The new pointer is computed and reduced locally, and
+// published with a single atomic store, so the consumer never sees an
+// intermediate value.
+func (r *Ringbuffer) advanceWrite(from, n uint32) {
+	atomic.StoreUint32(&r.write, r.mask2(from+n))
 
-	return nil
+	// whatever was written went into the space of any outstanding
+	// reservation, so it can't be committed anymore
+	r.reserved = 0
+
+	// the producer's copy of read may only lag it by what keeps the distance
+	// within the capacity, or, modulo 2*capacity, it aliases to a smaller
+	// one and Write overestimates the free space
+	if int(r.distance(r.cachedRead, from))+int(n) > len(r.buf) {
+		r.cachedRead = r.readPtr()
+	}
+
+	if atomic.LoadUint32(&r.readWaiting) == 1 {
+		wake(r.readable)
+	}
+}
+
+// advanceRead releases n consumed bytes, starting at the read pointer from,
+// back to the producer. In overwrite mode the caller must hold the storage
+// lock, so that the producer can't evict in between.
+func (r *Ringbuffer) advanceRead(from, n uint32) {
+	read := r.mask2(from + n)
+	atomic.StoreUint32(&r.read, read)
+
+	// the consumer's copy of write must never fall behind read, or Read
+	// sees a wrapped-around distance and copies out bytes that were never
+	// written. In overwrite mode Read reloads write every time anyway.
+	if n > r.distance(from, r.cachedWrite) {
+		r.cachedWrite = read
+	}
+
+	if atomic.LoadUint32(&r.writeWaiting) == 1 {
+		wake(r.writable)
+	}
+}
+
+// Write copies as many bytes from the provided []byte slice into the
+// ringbuffer as there is free space for. Data is copied to storage[write:],
+// and the write pointer is advanced by n bytes written.
+//
+// If there isn't enough space for the entire write, the bytes that fit are
+// stored and a *CapacityError is returned along with the short count.
+// Writing to a closed ringbuffer returns ErrClosed.
+//
+// A ringbuffer created with NewOverwritingRingbuffer never returns ErrFull,
+// see WriteOverwrite.
+func (r *Ringbuffer) Write(buf []byte) (n int, err error) {
+	if r.overwrite {
+		n, _, err = r.WriteOverwrite(buf)
+		return n, err
+	}
+	if r.isClosed() {
+		return 0, ErrClosed
+	}
+
+	// only the producer moves write, so it can be loaded once
+	write := r.writePtr()
+
+	emptyCount := r.Capacity() - int(r.distance(r.cachedRead, write))
+	if len(buf) > emptyCount {
+		// the consumer has probably read more since
+		r.cachedRead = r.readPtr()
+		emptyCount = r.Capacity() - int(r.distance(r.cachedRead, write))
+	}
+
+	if len(buf) > emptyCount {
+		err = &CapacityError{Requested: len(buf), Available: emptyCount}
+		buf = buf[:emptyCount]
+	}
+	if len(buf) == 0 {
+		return 0, err
+	}
+
+	first, second := r.regions(write, uint32(len(buf)))
+	copy(first, buf)
+	copy(second, buf[len(first):])
+
+	r.advanceWrite(write, uint32(len(buf)))
+
+	return len(buf), err
 }
 
 // Read fills the provided []byte slice with as much data as can fit. Data is
 // copied from  the ringbuffer's storage[read:], and the read pointer is
 // advanced by n bytes read.
 //
-// There are no errors - care must be taken for partial reads if the ringbuffer
-// doesn't have enough data. Best check the ringbuffer Size() method beforehand.
-func (r *Ringbuffer) Read(buf []byte) {
-	if r.Empty() {
-		return
+// Read never blocks. If the ringbuffer is empty it returns 0, ErrEmpty,
+// unless the ringbuffer has been closed, in which case it returns 0, io.EOF.
+// ErrEmpty tells consumers such as bufio.Reader and io.ReadFull to stop
+// instead of retrying in a busy loop; use ReadContext to wait for data. A
+// zero-length buf returns 0, nil.
+func (r *Ringbuffer) Read(buf []byte) (n int, err error) {
+	if len(buf) == 0 {
+		return 0, nil
 	}
+	r.lockStorage()
+	defer r.unlockStorage()
 
-	size := r.Size()
-	readCountTmp := len(buf)
-	if size < readCountTmp {
-		readCountTmp = r.Size()
+	// check closed before empty, so that data written before Close is always
+	// read before io.EOF is reported
+	closed := r.isClosed()
+	read := r.readPtr()
+
+	// in overwrite mode the producer moves read too, which can leave the
+	// cached write pointer behind it
+	size := int(r.distance(read, r.cachedWrite))
+	if r.overwrite || size < len(buf) {
+		// the producer has probably written more since
+		r.cachedWrite = r.writePtr()
+		size = int(r.distance(read, r.cachedWrite))
+	}
+
+	if size == 0 {
+		if closed {
+			return 0, io.EOF
+		}
+		return 0, ErrEmpty
 	}
-	readCount := uint32(readCountTmp)
 
+	readCount := len(buf)
+	if size < readCount {
+		readCount = size
+	}
+
+	first, second := r.regions(read, uint32(readCount))
+	copy(buf, first)
+	copy(buf[len(first):], second)
+
+	r.advanceRead(read, uint32(readCount))
+	return readCount, nil
+}
+
+// WriteOverwrite copies all of buf into the ringbuffer, dropping the oldest
+// bytes if there isn't enough free space. It returns the number of bytes
+// evicted to make room. If buf is larger than the capacity, only its last
+// Capacity() bytes are kept, and the rest count as evicted too.
+//
+// If the ringbuffer wasn't created with NewOverwritingRingbuffer, it is not
+// safe to evict while a consumer is reading, so WriteOverwrite behaves like
+// Write and never evicts.
+func (r *Ringbuffer) WriteOverwrite(buf []byte) (n, evicted int, err error) {
+	if !r.overwrite {
+		n, err = r.Write(buf)
+		return n, 0, err
+	}
+	if r.isClosed() {
+		return 0, 0, ErrClosed
+	}
+
+	n = len(buf)
 	capacity := uint32(len(r.buf))
-	readIdx := r.mask(r.readPtr())
+	if len(buf) > int(capacity) {
+		evicted = len(buf) - int(capacity)
+		buf = buf[evicted:]
+	}
+	if len(buf) == 0 {
+		return n, evicted, nil
+	}
+	desiredWrite := uint32(len(buf))
+
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	// the consumer only moves read under the lock too, so it can be loaded
+	// once
+	read := r.readPtr()
+	if emptyCount := capacity - r.distance(read, r.writePtr()); desiredWrite > emptyCount {
+		drop := desiredWrite - emptyCount
+		atomic.StoreUint32(&r.read, r.mask2(read+drop))
+		evicted += int(drop)
+	}
+
+	write := r.writePtr()
+	first, second := r.regions(write, desiredWrite)
+	copy(first, buf)
+	copy(second, buf[len(first):])
+
+	r.advanceWrite(write, desiredWrite)
+
+	return n, evicted, nil
+}
 
-	var remain uint32 = 0
-	var firstChunk uint32 = 0
-	possibleFirstChunk := capacity - readIdx
+// Reserve returns one or two writable slices of the ringbuffer's free space,
+// together exactly n bytes long, so the producer can fill them in place
+// instead of copying through Write. The second slice is only non-empty if
+// the reserved space wraps around the end of storage.
+//
+// Nothing is visible to the consumer until Commit is called. A new call to
+// Reserve replaces the previous reservation, and any other write drops it.
+// If there isn't room for n bytes, a *CapacityError is returned and nothing
+// is reserved.
+func (r *Ringbuffer) Reserve(n int) (first, second []byte, err error) {
+	if r.isClosed() {
+		return nil, nil, ErrClosed
+	}
+	r.reserved = 0
+	if n < 0 {
+		return nil, nil, ErrBadCount
+	}
+	if available := r.Capacity() - r.Size(); n > available {
+		return nil, nil, &CapacityError{Requested: n, Available: available}
+	}
+
+	r.reserved = n
+	r.reservedAt = r.writePtr()
+	first, second = r.regions(r.reservedAt, uint32(n))
+	return first, second, nil
+}
+
+// Commit makes the first n bytes of the last reservation visible to the
+// consumer, as if they had been passed to Write. It returns ErrBadCount if n
+// is larger than what was reserved, or if anything was written since Reserve,
+// which drops the reservation. Committing to a closed ringbuffer returns
+// ErrClosed, even for a reservation made before Close.
+func (r *Ringbuffer) Commit(n int) error {
+	if r.isClosed() {
+		r.reserved = 0
+		return ErrClosed
+	}
+	if n < 0 || n > r.reserved || r.writePtr() != r.reservedAt {
+		return ErrBadCount
+	}
 
-	if readCount > possibleFirstChunk {
-		remain = readCount - possibleFirstChunk
-		firstChunk = possibleFirstChunk
-	} else {
-		firstChunk = readCount
+	r.reserved = 0
+	if n > 0 {
+		r.advanceWrite(r.reservedAt, uint32(n))
 	}
+	return nil
+}
 
-	copy(buf, r.buf[readIdx:readIdx+firstChunk])
-	copy(buf[firstChunk:], r.buf[:remain])
+// PeekRegions returns one or two slices of storage holding all of the data
+// currently in the ringbuffer, oldest first, without consuming it. The second
+// slice is only non-empty if the data wraps around the end of storage.
+//
+// The slices alias the ringbuffer's storage, and are only valid until the
+// bytes are released with Consume. In overwrite mode the producer may
+// overwrite them at any time, racing with the caller's reads, so use Peek
+// there instead.
+func (r *Ringbuffer) PeekRegions() (first, second []byte) {
+	return r.regions(r.readPtr(), uint32(r.Size()))
+}
 
-	atomic.AddUint32(&r.read, uint32(readCount))
-	atomic.SwapUint32(&r.read, r.mask2(r.readPtr()))
+// Consume releases the oldest n bytes back to the producer, as if they had
+// been read. It is the counterpart to PeekRegions. It returns ErrBadCount if
+// n is larger than Size.
+func (r *Ringbuffer) Consume(n int) error {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	read := r.readPtr()
+	if n < 0 || n > int(r.distance(read, r.writePtr())) {
+		return ErrBadCount
+	}
+
+	if n > 0 {
+		r.advanceRead(read, uint32(n))
+	}
+	return nil
+}
+
+// ReadContext is like Read, but if the ringbuffer is empty it blocks until
+// there is data to read, the ringbuffer is closed, or ctx is done. If ctx is
+// done first, ctx.Err() is returned.
+//
+// Only the consumer may call ReadContext, and the SPSC rules still apply.
+func (r *Ringbuffer) ReadContext(ctx context.Context, buf []byte) (n int, err error) {
+	for {
+		n, err = r.Read(buf)
+		if err != ErrEmpty {
+			return n, err
+		}
+
+		// announce that we're waiting before checking again, so that a
+		// concurrent Write either sees the flag or its data is seen here
+		atomic.StoreUint32(&r.readWaiting, 1)
+		if !r.Empty() || r.isClosed() {
+			atomic.StoreUint32(&r.readWaiting, 0)
+			continue
+		}
+
+		select {
+		case <-r.readable:
+		case <-r.done:
+		case <-ctx.Done():
+			atomic.StoreUint32(&r.readWaiting, 0)
+			return 0, ctx.Err()
+		}
+		atomic.StoreUint32(&r.readWaiting, 0)
+	}
+}
+
+// WriteContext is like Write, but instead of returning ErrFull it blocks
+// until there is space for all of buf, the ringbuffer is closed, or ctx is
+// done. It returns the number of bytes written along with ErrClosed or
+// ctx.Err() if it stopped early.
+//
+// Only the producer may call WriteContext, and the SPSC rules still apply.
+func (r *Ringbuffer) WriteContext(ctx context.Context, buf []byte) (n int, err error) {
+	for {
+		var written int
+		written, err = r.Write(buf[n:])
+		n += written
+		if !errors.Is(err, ErrFull) {
+			return n, err
+		}
+
+		atomic.StoreUint32(&r.writeWaiting, 1)
+		if !r.Full() || r.isClosed() {
+			atomic.StoreUint32(&r.writeWaiting, 0)
+			continue
+		}
+
+		select {
+		case <-r.writable:
+		case <-r.done:
+		case <-ctx.Done():
+			atomic.StoreUint32(&r.writeWaiting, 0)
+			return n, ctx.Err()
+		}
+		atomic.StoreUint32(&r.writeWaiting, 0)
+	}
+}
+
+// Close marks the ringbuffer as closed. Subsequent writes return ErrClosed,
+// and once the remaining data has been read, Read returns io.EOF. Blocked
+// calls to ReadContext and WriteContext are woken up.
+func (r *Ringbuffer) Close() error {
+	if atomic.CompareAndSwapUint32(&r.closed, 0, 1) {
+		close(r.done)
+	}
+	return nil
 }
 
 // Drain creates and returns a []byte slice containing all data in the
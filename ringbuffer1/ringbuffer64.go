@@ -0,0 +1,156 @@
+package ringbuffer
+
+import (
+	"io"
+	"math"
+	"sync/atomic"
+
+	"github.com/bmkessler/fastdiv"
+)
+
+var _ io.ReadWriteCloser = (*Ringbuffer64)(nil)
+
+// A Ringbuffer64 is a Ringbuffer with uint64 read and write pointers, for
+// capacities beyond what uint32 pointers can address (see NewRingbuffer).
+//
+// It uses the same indexing strategy: the pointers are stored modulo
+// 2*capacity, and moduloed with capacity to index the storage, using the
+// 64-bit fastdiv. Each pointer is published with a single atomic store after
+// the data it covers has been copied. Like Ringbuffer, it is SPSC.
+type Ringbuffer64 struct {
+	// uint64s first, for 64-bit atomic alignment on 32-bit platforms
+	read   uint64
+	write  uint64
+	closed uint32
+	buf    []byte
+	n1     fastdiv.Uint64
+	n2     fastdiv.Uint64
+}
+
+// NewRingbuffer64 creates a ringbuffer with uint64 pointers and the
+// specified capacity. It returns ErrBadCapacity if the capacity isn't
+// positive, or is too large for the pointers to be stored modulo
+// 2*capacity.
+func NewRingbuffer64(capacity int) (*Ringbuffer64, error) {
+	if capacity <= 0 || uint64(capacity) > math.MaxUint64/3 {
+		return nil, ErrBadCapacity
+	}
+
+	buf := make([]byte, capacity)
+	return &Ringbuffer64{
+		buf: buf,
+		n1:  fastdiv.NewUint64(uint64(len(buf))),
+		n2:  fastdiv.NewUint64(2 * uint64(len(buf))),
+	}, nil
+}
+
+func (r *Ringbuffer64) mask(ptr uint64) uint64 {
+	return r.n1.Mod(ptr)
+}
+
+func (r *Ringbuffer64) mask2(ptr uint64) uint64 {
+	return r.n2.Mod(ptr)
+}
+
+func (r *Ringbuffer64) distance(read, write uint64) uint64 {
+	if write >= read {
+		return write - read
+	}
+	return write + 2*uint64(len(r.buf)) - read
+}
+
+// Size returns the number of bytes written and not yet read. Like
+// Ringbuffer.Size, it retries until it has loaded a consistent pair of
+// pointers.
+func (r *Ringbuffer64) Size() int {
+	for {
+		read := atomic.LoadUint64(&r.read)
+		write := atomic.LoadUint64(&r.write)
+		if atomic.LoadUint64(&r.read) == read {
+			return int(r.distance(read, write))
+		}
+	}
+}
+
+// Empty returns true if the ringbuffer is empty, false otherwise.
+func (r *Ringbuffer64) Empty() bool {
+	return atomic.LoadUint64(&r.read) == atomic.LoadUint64(&r.write)
+}
+
+// Full returns true if the ringbuffer is full, false otherwise.
+func (r *Ringbuffer64) Full() bool {
+	return r.Size() == r.Capacity()
+}
+
+// Capacity returns the capacity of the underlying []byte buf.
+func (r *Ringbuffer64) Capacity() int {
+	return len(r.buf)
+}
+
+// Write copies as many bytes from buf into the ringbuffer as there is free
+// space for, like Ringbuffer.Write. If not all of buf fits, a *CapacityError
+// is returned along with the short count.
+func (r *Ringbuffer64) Write(buf []byte) (n int, err error) {
+	if atomic.LoadUint32(&r.closed) == 1 {
+		return 0, ErrClosed
+	}
+
+	// only the producer moves write, so it can be loaded once
+	write := atomic.LoadUint64(&r.write)
+	emptyCount := len(r.buf) - int(r.distance(atomic.LoadUint64(&r.read), write))
+	if len(buf) > emptyCount {
+		err = &CapacityError{Requested: len(buf), Available: emptyCount}
+		buf = buf[:emptyCount]
+	}
+	if len(buf) == 0 {
+		return 0, err
+	}
+
+	first, second := split(r.buf, int(r.mask(write)), len(buf))
+	copy(first, buf)
+	copy(second, buf[len(first):])
+
+	atomic.StoreUint64(&r.write, r.mask2(write+uint64(len(buf))))
+
+	return len(buf), err
+}
+
+// Read fills buf with as much data as can fit, like Ringbuffer.Read. It never
+// blocks: it returns ErrEmpty if there is nothing to read, or io.EOF once
+// the ringbuffer is closed and empty.
+func (r *Ringbuffer64) Read(buf []byte) (n int, err error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	closed := atomic.LoadUint32(&r.closed) == 1
+
+	// only the consumer moves read, so it can be loaded once
+	read := atomic.LoadUint64(&r.read)
+	size := int(r.distance(read, atomic.LoadUint64(&r.write)))
+	if size == 0 {
+		if closed {
+			return 0, io.EOF
+		}
+		return 0, ErrEmpty
+	}
+
+	readCount := len(buf)
+	if size < readCount {
+		readCount = size
+	}
+
+	first, second := split(r.buf, int(r.mask(read)), readCount)
+	copy(buf, first)
+	copy(buf[len(first):], second)
+
+	atomic.StoreUint64(&r.read, r.mask2(read+uint64(readCount)))
+
+	return readCount, nil
+}
+
+// Close marks the ringbuffer as closed. Subsequent writes return ErrClosed,
+// and once the remaining data has been read, Read returns io.EOF.
+func (r *Ringbuffer64) Close() error {
+	atomic.StoreUint32(&r.closed, 1)
+	return nil
+}
@@ -0,0 +1,63 @@
+package ringbuffer_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestRingbuffer64BadCapacity(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		if _, err := ringbuffer.NewRingbuffer64(capacity); err != ringbuffer.ErrBadCapacity {
+			t.Errorf("Expected ErrBadCapacity for capacity %d, got %+v", capacity, err)
+		}
+	}
+}
+
+func TestRingbufferBadCapacityPanics(t *testing.T) {
+	for _, capacity := range []int{0, -1, math.MaxUint32 / 2} {
+		func() {
+			defer func() {
+				if recover() != ringbuffer.ErrBadCapacity {
+					t.Errorf("Expected NewRingbuffer(%d) to panic with ErrBadCapacity", capacity)
+				}
+			}()
+			ringbuffer.NewRingbuffer(capacity)
+		}()
+	}
+}
+
+func TestRingbuffer64ReadWrite(t *testing.T) {
+	ringbuf, err := ringbuffer.NewRingbuffer64(5)
+	if err != nil {
+		t.Fatalf("Didn't expect error creating ringbuf: %+v\n", err)
+	}
+
+	readBuf := make([]byte, 5)
+	for i := 0; i < 16; i++ {
+		_, err := ringbuf.Write([]byte{byte(i), byte(i + 1), byte(i + 2)})
+		if err != nil {
+			t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
+		}
+
+		n, _ := ringbuf.Read(readBuf)
+		if !bytes.Equal(readBuf[:n], []byte{byte(i), byte(i + 1), byte(i + 2)}) {
+			t.Errorf("Read back wrong data %v on write %d", readBuf[:n], i)
+		}
+	}
+
+	n, err := ringbuf.Write([]byte("hello, world!"))
+	if n != 5 || !errors.Is(err, ringbuffer.ErrFull) || !ringbuf.Full() {
+		t.Errorf("Expected short write filling the ringbuf, got %d, %+v", n, err)
+	}
+
+	ringbuf.Close()
+	ringbuf.Read(readBuf)
+	if _, err := ringbuf.Read(readBuf); err != io.EOF {
+		t.Errorf("Expected io.EOF on closed empty ringbuf, got %+v", err)
+	}
+}
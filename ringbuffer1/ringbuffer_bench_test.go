@@ -2,6 +2,8 @@ package ringbuffer_test
 
 import (
 	"encoding/binary"
+	"fmt"
+	"runtime"
 	"testing"
 
 	"github.com/sevagh/ringworm/ringbuffer1"
@@ -30,3 +32,69 @@ func BenchmarkManyRingbuffersBillionsOfIntegers(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkIndexing compares the bitmask indexing used for power-of-two
+// capacities with the fastdiv modulo used for every other capacity, using
+// capacities one byte apart.
+func BenchmarkIndexing(b *testing.B) {
+	for _, size := range []int{64, 4096, 1 << 16, 1 << 20} {
+		for _, bench := range []struct {
+			name     string
+			capacity int
+		}{
+			{"bitmask", size},
+			{"fastdiv", size - 1},
+		} {
+			b.Run(fmt.Sprintf("%s-%d", bench.name, bench.capacity), func(b *testing.B) {
+				ringbuf := ringbuffer.NewRingbuffer(bench.capacity)
+				writeBuf := make([]byte, 7)
+				readBuf := make([]byte, 7)
+
+				b.SetBytes(int64(len(writeBuf)))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					ringbuf.Write(writeBuf)
+					ringbuf.Read(readBuf)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkParallelProducerConsumer streams data from a producer goroutine to
+// a consumer goroutine, which is where false sharing between the read and
+// write pointers would show up. It needs GOMAXPROCS >= 2 to be meaningful.
+func BenchmarkParallelProducerConsumer(b *testing.B) {
+	for _, chunk := range []int{8, 64, 512} {
+		b.Run(fmt.Sprintf("chunk-%d", chunk), func(b *testing.B) {
+			ringbuf := ringbuffer.NewRingbuffer(1 << 16)
+			total := b.N * chunk
+
+			b.SetBytes(int64(chunk))
+			b.ResetTimer()
+
+			go func() {
+				writeBuf := make([]byte, chunk)
+				for remaining := total; remaining > 0; {
+					if remaining < len(writeBuf) {
+						writeBuf = writeBuf[:remaining]
+					}
+					n, _ := ringbuf.Write(writeBuf)
+					if n == 0 {
+						runtime.Gosched()
+					}
+					remaining -= n
+				}
+			}()
+
+			readBuf := make([]byte, chunk)
+			for read := 0; read < total; {
+				n, _ := ringbuf.Read(readBuf)
+				if n == 0 {
+					runtime.Gosched()
+				}
+				read += n
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package ringbuffer_test
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"testing"
 
 	"github.com/flyingmutant/rapid"
@@ -17,9 +19,15 @@ type ringbufferMachine struct {
 func (m *ringbufferMachine) Init(t *rapid.T) {
 	n := rapid.IntsRange(1, 20).Draw(t, "n").(int)
 	ringbufSizePowerOfTwo := 1 << n
-	m.r = ringbuffer.NewRingbuffer(ringbufSizePowerOfTwo)
 
-	t.Logf("Created ringbuffer with size 2^%d = %d\n", n, ringbufSizePowerOfTwo)
+	// cover both the bitmask and the fastdiv indexing
+	ringbufSize := ringbufSizePowerOfTwo
+	if rapid.Booleans().Draw(t, "nonPowerOfTwo").(bool) {
+		ringbufSize += rapid.IntsRange(1, ringbufSizePowerOfTwo-1).Draw(t, "extra").(int)
+	}
+	m.r = ringbuffer.NewRingbuffer(ringbufSize)
+
+	t.Logf("Created ringbuffer with size %d\n", ringbufSize)
 	m.n = n
 }
 
@@ -39,19 +47,18 @@ func (m *ringbufferMachine) Get(t *rapid.T) {
 }
 
 func (m *ringbufferMachine) Put(t *rapid.T) {
-	if m.r.Size() == (1 << m.n) {
+	if m.r.Full() {
 		t.Skip("ringbuffer full")
 	}
 
 	writeBuf := rapid.SlicesOfN(rapid.Bytes(), 0, m.n).Draw(t, "writeSlice").([]byte)
 
-	err := m.r.Write(writeBuf)
-	if len(writeBuf) != 0 {
-		if err == nil {
-			m.state = append(m.state, writeBuf)
-		} else {
-			t.Logf("ringbuffer full, discarded write %d for remaining capacity %d", writeBuf, m.r.Capacity()-m.r.Size())
-		}
+	n, err := m.r.Write(writeBuf)
+	if n != 0 {
+		m.state = append(m.state, writeBuf[:n])
+	}
+	if err != nil {
+		t.Logf("ringbuffer full, short write %d of %d", n, len(writeBuf))
 	}
 }
 
@@ -69,3 +76,59 @@ func (m *ringbufferMachine) Check(t *rapid.T) {
 func TestRingbufferProperty(t *testing.T) {
 	rapid.Check(t, rapid.StateMachine(&ringbufferMachine{}))
 }
+
+type msgMachine struct {
+	r     ringbuffer.Ringbuffer
+	state [][]byte
+}
+
+func (m *msgMachine) Init(t *rapid.T) {
+	n := rapid.IntsRange(1, 12).Draw(t, "n").(int)
+	m.r = ringbuffer.NewRingbuffer(1 << n)
+
+	t.Logf("Created ringbuffer with size 2^%d = %d\n", n, 1<<n)
+}
+
+func (m *msgMachine) Get(t *rapid.T) {
+	msg, err := m.r.ReadMsg()
+	if len(m.state) == 0 {
+		if err != ringbuffer.ErrEmpty {
+			t.Fatalf("expected ErrEmpty, got %v", err)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error reading msg: %v", err)
+	}
+	if !bytes.Equal(msg, m.state[0]) {
+		t.Fatalf("got invalid msg: %v vs expected %v", msg, m.state[0])
+	}
+	m.state = m.state[1:]
+}
+
+func (m *msgMachine) Put(t *rapid.T) {
+	msg := rapid.SlicesOfN(rapid.Bytes(), 0, 200).Draw(t, "msg").([]byte)
+
+	if err := m.r.WriteMsg(msg); err == nil {
+		m.state = append(m.state, msg)
+	} else if !errors.Is(err, ringbuffer.ErrFull) {
+		t.Fatalf("unexpected error writing msg: %v", err)
+	}
+}
+
+func (m *msgMachine) Check(t *rapid.T) {
+	var header [binary.MaxVarintLen64]byte
+	stateSum := 0
+	for _, msg := range m.state {
+		stateSum += len(msg) + binary.PutUvarint(header[:], uint64(len(msg)))
+	}
+
+	if m.r.Size() != stateSum {
+		t.Fatalf("ringbuffer size mismatch: %v vs expected %v", m.r.Size(), stateSum)
+	}
+}
+
+func TestRingbufferMsgProperty(t *testing.T) {
+	rapid.Check(t, rapid.StateMachine(&msgMachine{}))
+}
@@ -0,0 +1,80 @@
+package ringbuffer_test
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+// TestRingbufferStress streams a known byte pattern from a producer goroutine
+// to a consumer goroutine, while an observer checks that Size never goes out
+// of range. It raises GOMAXPROCS so the goroutines really run in parallel
+// where the hardware allows it. Run it with -race to check the memory
+// ordering of the pointer updates.
+func TestRingbufferStress(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(16))
+
+	const total = 1 << 16
+
+	for _, capacity := range []int{64, 61, 4096} {
+		for _, chunk := range []int{1, 7, 64} {
+			t.Run(fmt.Sprintf("capacity-%d-chunk-%d", capacity, chunk), func(t *testing.T) {
+				ringbuf := ringbuffer.NewRingbuffer(capacity)
+
+				var wg sync.WaitGroup
+				var done uint32
+				wg.Add(2)
+
+				go func() {
+					defer wg.Done()
+					writeBuf := make([]byte, chunk)
+					for written := 0; written < total; {
+						for i := range writeBuf {
+							writeBuf[i] = byte((written + i) % 251)
+						}
+						if total-written < len(writeBuf) {
+							writeBuf = writeBuf[:total-written]
+						}
+						n, _ := ringbuf.Write(writeBuf)
+						if n == 0 {
+							runtime.Gosched()
+						}
+						written += n
+					}
+				}()
+
+				go func() {
+					defer wg.Done()
+					for atomic.LoadUint32(&done) == 0 {
+						if size := ringbuf.Size(); size < 0 || size > capacity {
+							t.Errorf("Size out of range: %d", size)
+							return
+						}
+						runtime.Gosched()
+					}
+				}()
+
+				readBuf := make([]byte, chunk+3)
+				for read := 0; read < total; {
+					n, _ := ringbuf.Read(readBuf)
+					for i := 0; i < n; i++ {
+						if readBuf[i] != byte((read+i)%251) {
+							t.Fatalf("wrong byte at offset %d: %d", read+i, readBuf[i])
+						}
+					}
+					if n == 0 {
+						runtime.Gosched()
+					}
+					read += n
+				}
+
+				atomic.StoreUint32(&done, 1)
+				wg.Wait()
+			})
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package ringbuffer_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"io"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/gofuzz"
 	"github.com/sevagh/ringworm/ringbuffer1"
@@ -25,7 +30,7 @@ func TestRingbufferReadTooMuchOnlyDrains(t *testing.T) {
 	ringbuf := ringbuffer.NewRingbuffer(4)
 
 	writeBuf := []byte{0, 1, 2, 3}
-	err := ringbuf.Write(writeBuf)
+	_, err := ringbuf.Write(writeBuf)
 	if err != nil {
 		t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
 	}
@@ -53,7 +58,7 @@ func TestRingbufferReadTooMuchOnlyDrains(t *testing.T) {
 	}
 
 	writeBuf = []byte{4, 5, 6}
-	err = ringbuf.Write(writeBuf)
+	_, err = ringbuf.Write(writeBuf)
 	if err != nil {
 		t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
 	}
@@ -73,7 +78,7 @@ func TestRingbufferReadTooMuchOnlyDrains(t *testing.T) {
 	}
 
 	writeBuf = []byte{7, 8, 9}
-	err = ringbuf.Write(writeBuf)
+	_, err = ringbuf.Write(writeBuf)
 	if err != nil {
 		t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
 	}
@@ -125,7 +130,7 @@ func TestRingbufferWrite(t *testing.T) {
 	testData := "hello, world!"
 	dataBuf := []byte(testData)
 
-	err := ringbuf.Write(dataBuf)
+	_, err := ringbuf.Write(dataBuf)
 	if err != nil {
 		t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
 	}
@@ -150,7 +155,7 @@ func TestRingbufferWriteTooMuch(t *testing.T) {
 	testData := "hello, world!"
 	dataBuf := []byte(testData)
 
-	err := ringbuf.Write(dataBuf)
+	_, err := ringbuf.Write(dataBuf)
 	if err == nil {
 		t.Errorf("Expected an error here")
 	}
@@ -172,7 +177,7 @@ func TestRingbufferWriteMultiple(t *testing.T) {
 	for i := 0; i < len(testData); i++ {
 		dataBuf = []byte(testData[i])
 
-		err := ringbuf.Write(dataBuf)
+		_, err := ringbuf.Write(dataBuf)
 		if err != nil {
 			t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
 		}
@@ -201,7 +206,7 @@ func TestRingbufferWriteConcurrent(t *testing.T) {
 		var err error
 
 		for i := 0; i < 20; i++ {
-			err = ringbuf.Write(dataBuf)
+			_, err = ringbuf.Write(dataBuf)
 			if err != nil {
 				t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
 			}
@@ -235,7 +240,7 @@ func TestRingbufferDrain(t *testing.T) {
 	for i := 0; i < len(testData); i++ {
 		dataBuf := []byte(testData[i])
 
-		err := ringbuf.Write(dataBuf)
+		_, err := ringbuf.Write(dataBuf)
 		if err != nil {
 			t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
 		}
@@ -279,7 +284,7 @@ func TestRingbufferWriteptrAdvances(t *testing.T) {
 			t.Errorf("last 4 writes should not be ok. on write: %d", i)
 		}
 
-		err := ringbuf.Write(dataBuf)
+		_, err := ringbuf.Write(dataBuf)
 		if !expectError && err != nil {
 			t.Errorf("Didn't expect error when writing size %d to ringbuf with size %d, capacity %d: %+v\n", err, len(dataBuf), ringbuf.Size(), ringbuf.Capacity())
 		}
@@ -297,7 +302,7 @@ func TestRingbufferFuzzBytes(t *testing.T) {
 	f := fuzz.New().NumElements(1, 64)
 	f.Fuzz(&writeBuf)
 
-	err := ringbuf.Write(writeBuf)
+	_, err := ringbuf.Write(writeBuf)
 	if err != nil {
 		t.Errorf("error when writing to ringbuf: %+v\n", err)
 	}
@@ -311,3 +316,485 @@ func TestRingbufferFuzzBytes(t *testing.T) {
 		t.Errorf("expected to read same as what i wrote\n")
 	}
 }
+
+func TestRingbufferShortWrite(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(4)
+
+	n, err := ringbuf.Write([]byte("hello, world!"))
+	if !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected ErrFull, got %+v\n", err)
+	}
+	if n != 4 {
+		t.Errorf("Expected short write of 4 bytes, got %d\n", n)
+	}
+
+	var capErr *ringbuffer.CapacityError
+	if !errors.As(err, &capErr) || capErr.Requested != 13 || capErr.Available != 4 {
+		t.Errorf("Expected a CapacityError for 13 bytes with 4 available, got %+v\n", err)
+	}
+
+	ret := string(ringbuf.Drain())
+	if ret != "hell" {
+		t.Errorf("Expected the bytes that fit to be stored\n\texp: %+v\n\tgot: %+v\n", "hell", ret)
+	}
+}
+
+func TestRingbufferNonPowerOfTwoSize(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(3)
+	readBuf := make([]byte, 3)
+
+	for i := 0; i < 16; i++ {
+		_, err := ringbuf.Write([]byte{byte(i), byte(i + 1)})
+		if err != nil {
+			t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
+		}
+		if ringbuf.Size() != 2 {
+			t.Errorf("Expected ringbuf to have 2 size, got %d on write %d", ringbuf.Size(), i)
+		}
+
+		n, _ := ringbuf.Read(readBuf)
+		if n != 2 || readBuf[0] != byte(i) || readBuf[1] != byte(i+1) {
+			t.Errorf("Read back wrong data %v on write %d", readBuf[:n], i)
+		}
+		if ringbuf.Size() != 0 {
+			t.Errorf("Expected ringbuf to have 0 size, got %d on write %d", ringbuf.Size(), i)
+		}
+	}
+}
+
+func TestRingbufferClose(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+
+	readBuf := make([]byte, 16)
+	n, err := ringbuf.Read(readBuf)
+	if n != 0 || err != ringbuffer.ErrEmpty {
+		t.Errorf("Expected empty open ringbuf to return 0, ErrEmpty, got %d, %+v", n, err)
+	}
+
+	ringbuf.Write([]byte("abc"))
+	ringbuf.Close()
+
+	if _, err := ringbuf.Write([]byte("def")); err != ringbuffer.ErrClosed {
+		t.Errorf("Expected ErrClosed when writing to closed ringbuf, got %+v", err)
+	}
+
+	n, err = ringbuf.Read(readBuf)
+	if n != 3 || err != nil {
+		t.Errorf("Expected to read remaining data before EOF, got %d, %+v", n, err)
+	}
+
+	n, err = ringbuf.Read(readBuf)
+	if n != 0 || err != io.EOF {
+		t.Errorf("Expected io.EOF on closed empty ringbuf, got %d, %+v", n, err)
+	}
+}
+
+func TestRingbufferBufioTemporarilyEmpty(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+	br := bufio.NewReader(&ringbuf)
+
+	ringbuf.Write([]byte("hel"))
+	if line, err := br.ReadString('\n'); line != "hel" || err != ringbuffer.ErrEmpty {
+		t.Errorf("Expected partial line and ErrEmpty from empty ringbuf, got %q, %+v", line, err)
+	}
+
+	ringbuf.Write([]byte("lo\n"))
+	if line, err := br.ReadString('\n'); line != "lo\n" || err != nil {
+		t.Errorf("Expected rest of line once data arrived, got %q, %+v", line, err)
+	}
+
+	ringbuf.Close()
+	if _, err := br.ReadByte(); err != io.EOF {
+		t.Errorf("Expected EOF from closed empty ringbuf, got %+v", err)
+	}
+}
+
+func TestRingbufferIOCopy(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(64)
+
+	testData := "hello, world!"
+	written, err := io.Copy(&ringbuf, bytes.NewBufferString(testData))
+	if err != nil || written != int64(len(testData)) {
+		t.Errorf("Didn't expect error copying into ringbuf: %d, %+v\n", written, err)
+	}
+	ringbuf.Close()
+
+	var out bytes.Buffer
+	read, err := io.Copy(&out, &ringbuf)
+	if err != nil || read != int64(len(testData)) {
+		t.Errorf("Didn't expect error copying out of ringbuf: %d, %+v\n", read, err)
+	}
+
+	if out.String() != testData {
+		t.Errorf("Expected io.Copy round trip\n\texp: %+v\n\tgot: %+v\n", testData, out.String())
+	}
+}
+
+func TestRingbufferReadContextBlocks(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ringbuf.Write([]byte("abc"))
+	}()
+
+	readBuf := make([]byte, 16)
+	n, err := ringbuf.ReadContext(context.Background(), readBuf)
+	if err != nil {
+		t.Errorf("Didn't expect error from blocking read: %+v\n", err)
+	}
+	if string(readBuf[:n]) != "abc" {
+		t.Errorf("Expected blocking read to return written data, got %v", readBuf[:n])
+	}
+}
+
+func TestRingbufferReadContextCancel(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	n, err := ringbuf.ReadContext(ctx, make([]byte, 16))
+	if n != 0 || err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded on empty ringbuf, got %d, %+v", n, err)
+	}
+}
+
+func TestRingbufferReadContextClose(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ringbuf.Close()
+	}()
+
+	n, err := ringbuf.ReadContext(context.Background(), make([]byte, 16))
+	if n != 0 || err != io.EOF {
+		t.Errorf("Expected io.EOF when ringbuf is closed during read, got %d, %+v", n, err)
+	}
+}
+
+func TestRingbufferWriteContextCancel(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(4)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	n, err := ringbuf.WriteContext(ctx, []byte("hello, world!"))
+	if n != 4 || err != context.DeadlineExceeded {
+		t.Errorf("Expected 4 bytes and deadline exceeded on full ringbuf, got %d, %+v", n, err)
+	}
+}
+
+func TestRingbufferBlockingPipeline(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+
+	testData := make([]byte, 1<<16)
+	for i := range testData {
+		testData[i] = byte(i * 7)
+	}
+
+	go func() {
+		for i := 0; i < len(testData); i += 100 {
+			end := i + 100
+			if end > len(testData) {
+				end = len(testData)
+			}
+			_, err := ringbuf.WriteContext(context.Background(), testData[i:end])
+			if err != nil {
+				t.Errorf("Didn't expect error from blocking write: %+v\n", err)
+			}
+		}
+		ringbuf.Close()
+	}()
+
+	var out bytes.Buffer
+	readBuf := make([]byte, 7)
+	for {
+		n, err := ringbuf.ReadContext(context.Background(), readBuf)
+		out.Write(readBuf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Didn't expect error from blocking read: %+v\n", err)
+		}
+	}
+
+	if !bytes.Equal(out.Bytes(), testData) {
+		t.Errorf("expected to read same as what i wrote\n")
+	}
+}
+
+func TestRingbufferReserveCommit(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	// move the pointers so the next reservation wraps around
+	ringbuf.Write([]byte("abcdef"))
+	ringbuf.Read(make([]byte, 6))
+
+	first, second, err := ringbuf.Reserve(5)
+	if err != nil {
+		t.Fatalf("Didn't expect error when reserving: %+v\n", err)
+	}
+	if len(first) != 2 || len(second) != 3 {
+		t.Errorf("Expected reservation to wrap into 2 and 3 bytes, got %d and %d", len(first), len(second))
+	}
+
+	copy(first, "he")
+	copy(second, "llo")
+
+	if !ringbuf.Empty() {
+		t.Errorf("Expected reserved bytes to stay invisible until commit")
+	}
+
+	if err := ringbuf.Commit(6); err != ringbuffer.ErrBadCount {
+		t.Errorf("Expected ErrBadCount when committing more than reserved, got %+v", err)
+	}
+	if err := ringbuf.Commit(5); err != nil {
+		t.Errorf("Didn't expect error when committing: %+v\n", err)
+	}
+
+	ret := string(ringbuf.Drain())
+	if ret != "hello" {
+		t.Errorf("Expected committed bytes to be readable\n\texp: %+v\n\tgot: %+v\n", "hello", ret)
+	}
+
+	if _, _, err := ringbuf.Reserve(9); !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected ErrFull when reserving more than capacity, got %+v", err)
+	}
+}
+
+func TestRingbufferPeekRegionsConsume(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	ringbuf.Write([]byte("abcdef"))
+	ringbuf.Read(make([]byte, 4))
+	ringbuf.Write([]byte("ghij"))
+
+	first, second := ringbuf.PeekRegions()
+	if string(first) != "efgh" || string(second) != "ij" {
+		t.Errorf("Expected regions efgh and ij, got %s and %s", first, second)
+	}
+	if ringbuf.Size() != 6 {
+		t.Errorf("Expected peeking to not consume, got size %d", ringbuf.Size())
+	}
+
+	if err := ringbuf.Consume(7); err != ringbuffer.ErrBadCount {
+		t.Errorf("Expected ErrBadCount when consuming more than size, got %+v", err)
+	}
+	if err := ringbuf.Consume(5); err != nil {
+		t.Errorf("Didn't expect error when consuming: %+v\n", err)
+	}
+
+	first, second = ringbuf.PeekRegions()
+	if string(first) != "j" || len(second) != 0 {
+		t.Errorf("Expected a single region j, got %s and %s", first, second)
+	}
+}
+
+func TestRingbufferOverwrite(t *testing.T) {
+	ringbuf := ringbuffer.NewOverwritingRingbuffer(8)
+
+	n, evicted, err := ringbuf.WriteOverwrite([]byte("abcdef"))
+	if n != 6 || evicted != 0 || err != nil {
+		t.Errorf("Expected write that fits to evict nothing, got %d, %d, %+v", n, evicted, err)
+	}
+
+	n, evicted, err = ringbuf.WriteOverwrite([]byte("ghijk"))
+	if n != 5 || evicted != 3 || err != nil {
+		t.Errorf("Expected write to evict 3 bytes, got %d, %d, %+v", n, evicted, err)
+	}
+	if !ringbuf.Full() {
+		t.Errorf("Expected ringbuf to be full after evicting")
+	}
+
+	ret := string(ringbuf.Drain())
+	if ret != "defghijk" {
+		t.Errorf("Expected the newest bytes to be kept\n\texp: %+v\n\tgot: %+v\n", "defghijk", ret)
+	}
+
+	ringbuf.Write([]byte("xy"))
+	n, evicted, err = ringbuf.WriteOverwrite([]byte("0123456789"))
+	if n != 10 || evicted != 4 || err != nil {
+		t.Errorf("Expected oversized write to evict 4 bytes, got %d, %d, %+v", n, evicted, err)
+	}
+
+	ret = string(ringbuf.Drain())
+	if ret != "23456789" {
+		t.Errorf("Expected the last capacity bytes to be kept\n\texp: %+v\n\tgot: %+v\n", "23456789", ret)
+	}
+}
+
+func TestRingbufferOverwriteWrite(t *testing.T) {
+	ringbuf := ringbuffer.NewOverwritingRingbuffer(4)
+
+	for i := 0; i < 10; i++ {
+		n, err := ringbuf.Write([]byte{byte(i)})
+		if n != 1 || err != nil {
+			t.Errorf("Expected Write to never fail in overwrite mode, got %d, %+v", n, err)
+		}
+	}
+
+	ret := ringbuf.Drain()
+	if !bytes.Equal(ret, []byte{6, 7, 8, 9}) {
+		t.Errorf("Expected the last 4 writes to be kept, got %v", ret)
+	}
+}
+
+func TestRingbufferWriteOverwriteNotEnabled(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(4)
+
+	n, evicted, err := ringbuf.WriteOverwrite([]byte("hello"))
+	if n != 4 || evicted != 0 || !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected WriteOverwrite to behave like Write, got %d, %d, %+v", n, evicted, err)
+	}
+}
+
+func TestRingbufferOverwriteConcurrent(t *testing.T) {
+	ringbuf := ringbuffer.NewOverwritingRingbuffer(64)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		writeBuf := make([]byte, 5)
+		next := byte(0)
+		for i := 0; i < 100000; i++ {
+			for j := range writeBuf {
+				writeBuf[j] = next
+				next++
+			}
+			ringbuf.Write(writeBuf)
+		}
+		ringbuf.Close()
+	}()
+
+	// bytes are always consecutive within a read, evictions only cause gaps
+	// between reads
+	readBuf := make([]byte, 7)
+	peekBuf := make([]byte, 7)
+	for {
+		n, _ := ringbuf.Peek(peekBuf)
+		for i := 1; i < n; i++ {
+			if peekBuf[i] != peekBuf[i-1]+1 {
+				t.Fatalf("Peek torn data %v", peekBuf[:n])
+			}
+		}
+
+		n, err := ringbuf.Read(readBuf)
+		if err == io.EOF {
+			break
+		}
+		for i := 1; i < n; i++ {
+			if readBuf[i] != readBuf[i-1]+1 {
+				t.Fatalf("Read torn data %v", readBuf[:n])
+			}
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestRingbufferConsumerOpsThenRead(t *testing.T) {
+	// each of these moves the read pointer without going through Read
+	skips := map[string]func(r *ringbuffer.Ringbuffer){
+		"Discard": func(r *ringbuffer.Ringbuffer) { r.Discard(4) },
+		"Consume": func(r *ringbuffer.Ringbuffer) { r.Consume(4) },
+		"Clear":   func(r *ringbuffer.Ringbuffer) { r.Clear() },
+		"ReadSlice": func(r *ringbuffer.Ringbuffer) {
+			r.ReadSlice('d')
+		},
+		"Draining": func(r *ringbuffer.Ringbuffer) {
+			for range r.Draining() {
+			}
+		},
+	}
+
+	for name, skip := range skips {
+		for _, capacity := range []int{16, 6} {
+			ringbuf := ringbuffer.NewRingbuffer(capacity)
+
+			for round := 0; round < 10; round++ {
+				ringbuf.Write([]byte("abcd"))
+				skip(&ringbuf)
+
+				n, err := ringbuf.Read(make([]byte, 8))
+				if n != 0 || err != ringbuffer.ErrEmpty {
+					t.Fatalf("%s, capacity %d: Expected nothing to read after skipping everything, got %d, %+v", name, capacity, n, err)
+				}
+				if size := ringbuf.Size(); size != 0 {
+					t.Fatalf("%s, capacity %d: Expected size 0, got %d", name, capacity, size)
+				}
+
+				ringbuf.Write([]byte("ef"))
+				buf := make([]byte, 8)
+				n, _ = ringbuf.Read(buf)
+				if string(buf[:n]) != "ef" {
+					t.Fatalf("%s, capacity %d: Expected to read ef, got %q", name, capacity, buf[:n])
+				}
+			}
+		}
+	}
+}
+
+func TestRingbufferCommitThenWrite(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(3)
+
+	// the producer's cached read pointer goes stale over two commits, and
+	// modulo 2*capacity it would alias to an empty ringbuffer
+	ringbuf.Reserve(3)
+	ringbuf.Commit(3)
+	ringbuf.Read(make([]byte, 3))
+	ringbuf.Reserve(3)
+	ringbuf.Commit(3)
+
+	if n, err := ringbuf.Write([]byte("QQ")); n != 0 || !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected writing to a full ringbuf to fail, got %d, %+v", n, err)
+	}
+	if size := ringbuf.Size(); size != 3 {
+		t.Errorf("Expected size 3, got %d", size)
+	}
+}
+
+func TestRingbufferCommitAfterClose(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	ringbuf.Reserve(4)
+	ringbuf.Close()
+	if err := ringbuf.Commit(4); err != ringbuffer.ErrClosed {
+		t.Errorf("Expected ErrClosed committing after close, got %+v", err)
+	}
+	if n, err := ringbuf.Read(make([]byte, 8)); n != 0 || err != io.EOF {
+		t.Errorf("Expected the failed commit to publish nothing, got %d, %+v", n, err)
+	}
+}
+
+func TestRingbufferWriteDropsReservation(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	ringbuf.Reserve(8)
+	ringbuf.Write([]byte("abc"))
+	if err := ringbuf.Commit(8); err != ringbuffer.ErrBadCount {
+		t.Errorf("Expected ErrBadCount committing after a write, got %+v", err)
+	}
+	if size := ringbuf.Size(); size != 3 {
+		t.Errorf("Expected the failed commit to publish nothing, got size %d", size)
+	}
+
+	ringbuf.Reserve(2)
+	ringbuf.WriteMsg([]byte("d"))
+	if err := ringbuf.Commit(2); err != ringbuffer.ErrBadCount {
+		t.Errorf("Expected ErrBadCount committing after WriteMsg, got %+v", err)
+	}
+
+	first, _, _ := ringbuf.Reserve(1)
+	first[0] = 'e'
+	if err := ringbuf.Commit(1); err != nil {
+		t.Errorf("Didn't expect error committing a fresh reservation: %+v", err)
+	}
+	if size := ringbuf.Size(); size != 6 {
+		t.Errorf("Expected size 6, got %d", size)
+	}
+}
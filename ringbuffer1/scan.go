@@ -0,0 +1,158 @@
+package ringbuffer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// indexByte returns the offset of the first c in the size bytes after read,
+// or -1 if there is none. The second region is searched after the first, so
+// the offset counts across the end of storage.
+func (r *Ringbuffer) indexByte(read, size uint32, c byte) int {
+	first, second := r.regions(read, size)
+	if i := bytes.IndexByte(first, c); i >= 0 {
+		return i
+	}
+	if i := bytes.IndexByte(second, c); i >= 0 {
+		return len(first) + i
+	}
+	return -1
+}
+
+// copyOut returns a copy of the count bytes after read.
+func (r *Ringbuffer) copyOut(read, count uint32) []byte {
+	buf := make([]byte, count)
+	first, second := r.regions(read, count)
+	copy(buf, first)
+	copy(buf[len(first):], second)
+	return buf
+}
+
+// IndexByte returns the offset of the first instance of c in the ringbuffer,
+// counted from the oldest byte, or -1 if c isn't present. Nothing is
+// consumed.
+//
+// Only the consumer should call IndexByte, since a concurrent Read would
+// move the offset out from under the caller.
+func (r *Ringbuffer) IndexByte(c byte) int {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	read := r.readPtr()
+	return r.indexByte(read, r.distance(read, r.writePtr()), c)
+}
+
+// ReadSlice reads until the first occurrence of delim, and returns a copy of
+// the data up to and including the delimiter. Unlike Read, nothing is
+// consumed until a delimiter has been written, so a partial token stays in
+// the ringbuffer until the producer completes it.
+//
+// If there is no delimiter yet, ReadSlice returns ErrEmpty, or ErrFull if the
+// ringbuffer is full, since the token can then never be completed. Once the
+// ringbuffer has been closed, the remaining data is returned along with
+// io.EOF, like bufio.Reader.ReadSlice.
+func (r *Ringbuffer) ReadSlice(delim byte) ([]byte, error) {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	closed := r.isClosed()
+	read := r.readPtr()
+	size := r.distance(read, r.writePtr())
+
+	var err error
+	count := uint32(r.indexByte(read, size, delim) + 1)
+	if count == 0 {
+		switch {
+		case closed:
+			if size == 0 {
+				return nil, io.EOF
+			}
+			count, err = size, io.EOF
+		case int(size) == r.Capacity():
+			return nil, ErrFull
+		default:
+			return nil, ErrEmpty
+		}
+	}
+
+	line := r.copyOut(read, count)
+	r.advanceRead(read, count)
+	return line, err
+}
+
+// ReadLine returns the next line from the ringbuffer, without the trailing
+// "\n" or "\r\n". It consumes nothing until a full line is available, and
+// returns the same errors as ReadSlice, except that a final line without a
+// line ending is returned with a nil error once the ringbuffer is closed.
+func (r *Ringbuffer) ReadLine() ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line = bytes.TrimSuffix(line, []byte{'\n'})
+	return bytes.TrimSuffix(line, []byte{'\r'}), nil
+}
+
+// ReadToken returns the next token found by split, which can be any
+// bufio.SplitFunc, such as bufio.ScanLines or bufio.ScanWords. The split
+// function sees all of the data in the ringbuffer as a single slice, and the
+// bytes it asks to advance over are consumed only once it returns a token.
+// atEOF is true once the ringbuffer has been closed.
+//
+// If split needs more data, ReadToken returns ErrEmpty, or ErrFull if the
+// ringbuffer is full. Once the ringbuffer is closed and split has no more
+// tokens, it returns io.EOF. Errors from split are returned as they are, and
+// consume nothing, except for bufio.ErrFinalToken, which is returned along
+// with its token.
+//
+// The data is handed to split without copying, unless it wraps around the
+// end of storage.
+func (r *Ringbuffer) ReadToken(split bufio.SplitFunc) ([]byte, error) {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	for {
+		closed := r.isClosed()
+		read := r.readPtr()
+		size := r.distance(read, r.writePtr())
+
+		data, second := r.regions(read, size)
+		if len(second) > 0 {
+			data = r.copyOut(read, size)
+		}
+
+		advance, token, err := split(data, closed)
+		if err != nil && err != bufio.ErrFinalToken {
+			return nil, err
+		}
+		if advance < 0 || advance > len(data) {
+			return nil, ErrBadCount
+		}
+
+		if advance == 0 && token == nil && err == nil {
+			switch {
+			case closed:
+				return nil, io.EOF
+			case int(size) == r.Capacity():
+				return nil, ErrFull
+			default:
+				return nil, ErrEmpty
+			}
+		}
+
+		// token may alias storage that is released by advancing
+		if token != nil {
+			token = append(make([]byte, 0, len(token)), token...)
+		}
+		r.advanceRead(read, uint32(advance))
+		if token != nil || err != nil {
+			return token, err
+		}
+		// split only skipped over some data, look for a token after it
+	}
+}
@@ -0,0 +1,126 @@
+package ringbuffer_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+// newWrappedRingbuffer returns a ringbuffer holding data, stored so that it
+// wraps around the end of storage.
+func newWrappedRingbuffer(capacity int, data string) ringbuffer.Ringbuffer {
+	ringbuf := ringbuffer.NewRingbuffer(capacity)
+	ringbuf.Write(make([]byte, capacity-2))
+	ringbuf.Read(make([]byte, capacity-2))
+	ringbuf.Write([]byte(data))
+	return ringbuf
+}
+
+func TestRingbufferIndexByte(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(8, "abc\ndef")
+
+	if i := ringbuf.IndexByte('\n'); i != 3 {
+		t.Errorf("Expected to find newline at 3 across the wraparound, got %d", i)
+	}
+	if i := ringbuf.IndexByte('a'); i != 0 {
+		t.Errorf("Expected to find a at 0, got %d", i)
+	}
+	if i := ringbuf.IndexByte('x'); i != -1 {
+		t.Errorf("Expected not to find x, got %d", i)
+	}
+	if ringbuf.Size() != 7 {
+		t.Errorf("Expected IndexByte not to consume, got size %d", ringbuf.Size())
+	}
+}
+
+func TestRingbufferReadLine(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(16, "one\r\ntwo\nthr")
+
+	expected := []string{"one", "two"}
+	for _, exp := range expected {
+		line, err := ringbuf.ReadLine()
+		if err != nil || string(line) != exp {
+			t.Errorf("Expected to read line %s, got %s, %+v", exp, line, err)
+		}
+	}
+
+	// the partial line stays in the ringbuffer until it's completed
+	if _, err := ringbuf.ReadLine(); err != ringbuffer.ErrEmpty {
+		t.Errorf("Expected ErrEmpty for a partial line, got %+v", err)
+	}
+	if ringbuf.Size() != 3 {
+		t.Errorf("Expected partial line to be kept, got size %d", ringbuf.Size())
+	}
+
+	ringbuf.Write([]byte("ee\nfour"))
+	if line, err := ringbuf.ReadLine(); err != nil || string(line) != "three" {
+		t.Errorf("Expected to read completed line three, got %s, %+v", line, err)
+	}
+
+	ringbuf.Close()
+	if line, err := ringbuf.ReadLine(); err != nil || string(line) != "four" {
+		t.Errorf("Expected final unterminated line four, got %s, %+v", line, err)
+	}
+	if _, err := ringbuf.ReadLine(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last line, got %+v", err)
+	}
+}
+
+func TestRingbufferReadSliceFull(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(4)
+	ringbuf.Write([]byte("abcd"))
+
+	if _, err := ringbuf.ReadSlice(';'); !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected ErrFull when a token can't fit, got %+v", err)
+	}
+
+	ringbuf.Close()
+	line, err := ringbuf.ReadSlice(';')
+	if string(line) != "abcd" || err != io.EOF {
+		t.Errorf("Expected the rest with io.EOF once closed, got %s, %+v", line, err)
+	}
+}
+
+func TestRingbufferReadToken(t *testing.T) {
+	ringbuf := newWrappedRingbuffer(16, "  hello wor")
+
+	token, err := ringbuf.ReadToken(bufio.ScanWords)
+	if err != nil || string(token) != "hello" {
+		t.Errorf("Expected to read word hello, got %s, %+v", token, err)
+	}
+
+	if _, err := ringbuf.ReadToken(bufio.ScanWords); err != ringbuffer.ErrEmpty {
+		t.Errorf("Expected ErrEmpty for a partial word, got %+v", err)
+	}
+
+	ringbuf.Write([]byte("ld "))
+	ringbuf.Close()
+
+	token, err = ringbuf.ReadToken(bufio.ScanWords)
+	if err != nil || string(token) != "world" {
+		t.Errorf("Expected to read word world, got %s, %+v", token, err)
+	}
+	if _, err := ringbuf.ReadToken(bufio.ScanWords); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last word, got %+v", err)
+	}
+}
+
+func TestRingbufferReadTokenError(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+	ringbuf.Write([]byte("abc"))
+
+	errSplit := errors.New("bad token")
+	split := func(data []byte, atEOF bool) (int, []byte, error) {
+		return 0, nil, errSplit
+	}
+
+	if _, err := ringbuf.ReadToken(split); err != errSplit {
+		t.Errorf("Expected the split error, got %+v", err)
+	}
+	if ringbuf.Size() != 3 {
+		t.Errorf("Expected a failed split not to consume, got size %d", ringbuf.Size())
+	}
+}
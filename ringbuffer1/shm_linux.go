@@ -0,0 +1,249 @@
+//go:build linux
+
+package ringbuffer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"github.com/bmkessler/fastdiv"
+)
+
+// Layout of a shared ringbuffer file. The cursors are on separate cache
+// lines, and the data region starts after the header.
+const (
+	shmMagic   = 0x52575242 // "RWRB"
+	shmVersion = 1
+
+	shmMagicOff    = 0
+	shmVersionOff  = 4
+	shmCapacityOff = 8
+	shmClosedOff   = 12
+	shmWriteOff    = 64
+	shmReadOff     = 128
+	shmHeaderSize  = 192
+)
+
+// ErrBadSharedRingbuffer is returned by OpenSharedRingbuffer when the file
+// doesn't hold a shared ringbuffer of a supported version.
+var ErrBadSharedRingbuffer = errors.New("ringbuffer: not a shared ringbuffer")
+
+var _ io.ReadWriteCloser = (*SharedRingbuffer)(nil)
+
+// A SharedRingbuffer is a ringbuffer whose header and storage live in a
+// memory-mapped file, so that a producer and a consumer in different
+// processes can attach to the same ring. The file can be a regular file, one
+// in /dev/shm, or a memfd passed to the other process.
+//
+// It uses the same indexing strategy as Ringbuffer: read and write pointers
+// stored modulo 2*capacity in the header, updated with sync/atomic. Each
+// pointer is published with a single atomic store, after the data it covers
+// has been copied. The SPSC rules apply across processes: one process writes
+// and one process reads.
+type SharedRingbuffer struct {
+	mem    []byte
+	read   *uint32
+	write  *uint32
+	closed *uint32
+	buf    []byte
+	n1     fastdiv.Uint32
+	n2     fastdiv.Uint32
+}
+
+// CreateSharedRingbuffer lays out a new shared ringbuffer with the specified
+// capacity in f, truncating it to the right size, and maps it into memory.
+// The other process attaches to it with OpenSharedRingbuffer.
+func CreateSharedRingbuffer(f *os.File, capacity int) (*SharedRingbuffer, error) {
+	if capacity <= 0 || capacity > maxCapacity {
+		return nil, ErrBadCapacity
+	}
+	if err := f.Truncate(int64(shmHeaderSize + capacity)); err != nil {
+		return nil, err
+	}
+
+	mem, err := syscall.Mmap(int(f.Fd()), 0, shmHeaderSize+capacity, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newSharedRingbuffer(mem, capacity)
+	atomic.StoreUint32(r.read, 0)
+	atomic.StoreUint32(r.write, 0)
+	atomic.StoreUint32(r.closed, 0)
+	*r.header(shmCapacityOff) = uint32(capacity)
+	*r.header(shmVersionOff) = shmVersion
+	// write the magic last, so a concurrent open never sees a half-built header
+	atomic.StoreUint32(r.header(shmMagicOff), shmMagic)
+
+	return r, nil
+}
+
+// OpenSharedRingbuffer maps the shared ringbuffer stored in f, which was
+// created with CreateSharedRingbuffer, possibly by another process.
+func OpenSharedRingbuffer(f *os.File) (*SharedRingbuffer, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() <= shmHeaderSize || fi.Size() > shmHeaderSize+maxCapacity {
+		return nil, ErrBadSharedRingbuffer
+	}
+
+	mem, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	magic := atomic.LoadUint32((*uint32)(unsafe.Pointer(&mem[shmMagicOff])))
+	version := *(*uint32)(unsafe.Pointer(&mem[shmVersionOff]))
+	capacity := *(*uint32)(unsafe.Pointer(&mem[shmCapacityOff]))
+	if magic != shmMagic || version != shmVersion || int64(capacity) != fi.Size()-shmHeaderSize {
+		syscall.Munmap(mem)
+		return nil, ErrBadSharedRingbuffer
+	}
+
+	return newSharedRingbuffer(mem, int(capacity)), nil
+}
+
+func newSharedRingbuffer(mem []byte, capacity int) *SharedRingbuffer {
+	r := &SharedRingbuffer{
+		mem: mem,
+		buf: mem[shmHeaderSize : shmHeaderSize+capacity],
+		n1:  fastdiv.NewUint32(uint32(capacity)),
+		n2:  fastdiv.NewUint32(uint32(2 * capacity)),
+	}
+	r.read = r.header(shmReadOff)
+	r.write = r.header(shmWriteOff)
+	r.closed = r.header(shmClosedOff)
+	return r
+}
+
+// header returns the uint32 at offset off in the header. The mapping is page
+// aligned, so all the header fields are aligned for atomic access.
+func (r *SharedRingbuffer) header(off int) *uint32 {
+	return (*uint32)(unsafe.Pointer(&r.mem[off]))
+}
+
+func (r *SharedRingbuffer) mask(ptr uint32) uint32 {
+	return r.n1.Mod(ptr)
+}
+
+func (r *SharedRingbuffer) mask2(ptr uint32) uint32 {
+	return r.n2.Mod(ptr)
+}
+
+func (r *SharedRingbuffer) distance(read, write uint32) uint32 {
+	if write >= read {
+		return write - read
+	}
+	return write + 2*uint32(len(r.buf)) - read
+}
+
+// Size returns the number of bytes written and not yet read. Like
+// Ringbuffer.Size, it retries until it has loaded a consistent pair of
+// pointers.
+func (r *SharedRingbuffer) Size() int {
+	for {
+		read := atomic.LoadUint32(r.read)
+		write := atomic.LoadUint32(r.write)
+		if atomic.LoadUint32(r.read) == read {
+			return int(r.distance(read, write))
+		}
+	}
+}
+
+// Empty returns true if the ringbuffer is empty, false otherwise.
+func (r *SharedRingbuffer) Empty() bool {
+	return r.Size() == 0
+}
+
+// Full returns true if the ringbuffer is full, false otherwise.
+func (r *SharedRingbuffer) Full() bool {
+	return r.Size() == r.Capacity()
+}
+
+// Capacity returns the capacity of the shared data region.
+func (r *SharedRingbuffer) Capacity() int {
+	return len(r.buf)
+}
+
+// Write copies as many bytes from buf into the ringbuffer as there is free
+// space for, like Ringbuffer.Write. If not all of buf fits, a *CapacityError
+// is returned along with the short count.
+func (r *SharedRingbuffer) Write(buf []byte) (n int, err error) {
+	if atomic.LoadUint32(r.closed) == 1 {
+		return 0, ErrClosed
+	}
+
+	// only this process moves write, so it can be loaded once
+	write := atomic.LoadUint32(r.write)
+	emptyCount := len(r.buf) - int(r.distance(atomic.LoadUint32(r.read), write))
+	if len(buf) > emptyCount {
+		err = &CapacityError{Requested: len(buf), Available: emptyCount}
+		buf = buf[:emptyCount]
+	}
+	if len(buf) == 0 {
+		return 0, err
+	}
+
+	first, second := split(r.buf, int(r.mask(write)), len(buf))
+	copy(first, buf)
+	copy(second, buf[len(first):])
+
+	atomic.StoreUint32(r.write, r.mask2(write+uint32(len(buf))))
+
+	return len(buf), err
+}
+
+// Read fills buf with as much data as can fit, like Ringbuffer.Read. It never
+// blocks: it returns ErrEmpty if there is nothing to read, or io.EOF once
+// the ringbuffer is closed and empty.
+func (r *SharedRingbuffer) Read(buf []byte) (n int, err error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	closed := atomic.LoadUint32(r.closed) == 1
+
+	// only this process moves read, so it can be loaded once
+	read := atomic.LoadUint32(r.read)
+	size := int(r.distance(read, atomic.LoadUint32(r.write)))
+	if size == 0 {
+		if closed {
+			return 0, io.EOF
+		}
+		return 0, ErrEmpty
+	}
+
+	readCount := len(buf)
+	if size < readCount {
+		readCount = size
+	}
+
+	first, second := split(r.buf, int(r.mask(read)), readCount)
+	copy(buf, first)
+	copy(buf[len(first):], second)
+
+	atomic.StoreUint32(r.read, r.mask2(read+uint32(readCount)))
+
+	return readCount, nil
+}
+
+// Close marks the shared ringbuffer as closed, for both processes.
+// Subsequent writes return ErrClosed, and once the remaining data has been
+// read, Read returns io.EOF. It doesn't unmap the memory, see Detach.
+func (r *SharedRingbuffer) Close() error {
+	atomic.StoreUint32(r.closed, 1)
+	return nil
+}
+
+// Detach unmaps the shared memory. The ringbuffer must not be used after
+// Detach, but the other process can keep using its own mapping.
+func (r *SharedRingbuffer) Detach() error {
+	mem := r.mem
+	r.mem, r.buf = nil, nil
+	return syscall.Munmap(mem)
+}
@@ -0,0 +1,162 @@
+//go:build linux
+
+package ringbuffer_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func createShared(t *testing.T, capacity int) (string, *ringbuffer.SharedRingbuffer) {
+	f, err := os.CreateTemp("", "ringworm")
+	if err != nil {
+		t.Fatalf("failed to create ring file: %+v", err)
+	}
+	defer f.Close()
+
+	ringbuf, err := ringbuffer.CreateSharedRingbuffer(f, capacity)
+	if err != nil {
+		t.Fatalf("failed to create shared ringbuffer: %+v", err)
+	}
+	return f.Name(), ringbuf
+}
+
+func openShared(t *testing.T, path string) *ringbuffer.SharedRingbuffer {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("failed to open ring file: %+v", err)
+	}
+	defer f.Close()
+
+	ringbuf, err := ringbuffer.OpenSharedRingbuffer(f)
+	if err != nil {
+		t.Fatalf("failed to open shared ringbuffer: %+v", err)
+	}
+	return ringbuf
+}
+
+func TestSharedRingbufferTwoMappings(t *testing.T) {
+	path, producer := createShared(t, 8)
+	defer os.Remove(path)
+	defer producer.Detach()
+
+	consumer := openShared(t, path)
+	defer consumer.Detach()
+
+	if consumer.Capacity() != 8 {
+		t.Errorf("Expected attached ringbuf to have 8 capacity, got %d", consumer.Capacity())
+	}
+
+	readBuf := make([]byte, 8)
+	for i := 0; i < 4; i++ {
+		_, err := producer.Write([]byte("hello"))
+		if err != nil {
+			t.Errorf("Didn't expect error when writing to ringbuf: %+v\n", err)
+		}
+
+		n, _ := consumer.Read(readBuf)
+		if string(readBuf[:n]) != "hello" {
+			t.Errorf("Expected to read through the other mapping, got %s", readBuf[:n])
+		}
+	}
+
+	n, err := producer.Write([]byte("hello, world!"))
+	if n != 8 || !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected short write on full ringbuf, got %d, %+v", n, err)
+	}
+
+	producer.Close()
+	consumer.Read(readBuf)
+	if _, err := consumer.Read(readBuf); err != io.EOF {
+		t.Errorf("Expected io.EOF after the producer closed, got %+v", err)
+	}
+}
+
+func TestSharedRingbufferOpenInvalid(t *testing.T) {
+	f, err := os.CreateTemp("", "ringworm")
+	if err != nil {
+		t.Fatalf("failed to create file: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.Write(bytes.Repeat([]byte{0xff}, 512)); err != nil {
+		t.Fatalf("failed to write file: %+v", err)
+	}
+
+	if _, err := ringbuffer.OpenSharedRingbuffer(f); err != ringbuffer.ErrBadSharedRingbuffer {
+		t.Errorf("Expected ErrBadSharedRingbuffer, got %+v", err)
+	}
+}
+
+// TestSharedRingbufferChild is the producer half of
+// TestSharedRingbufferCrossProcess, run in a child process.
+func TestSharedRingbufferChild(t *testing.T) {
+	path := os.Getenv("RINGWORM_SHM_PATH")
+	if path == "" {
+		t.Skip("only run as a child of TestSharedRingbufferCrossProcess")
+	}
+
+	ringbuf := openShared(t, path)
+	defer ringbuf.Detach()
+
+	testData := sharedTestData()
+	for len(testData) > 0 {
+		n, _ := ringbuf.Write(testData)
+		testData = testData[n:]
+		if n == 0 {
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+	ringbuf.Close()
+}
+
+func sharedTestData() []byte {
+	testData := make([]byte, 1<<16)
+	for i := range testData {
+		testData[i] = byte(i * 7)
+	}
+	return testData
+}
+
+func TestSharedRingbufferCrossProcess(t *testing.T) {
+	path, ringbuf := createShared(t, 100)
+	defer os.Remove(path)
+	defer ringbuf.Detach()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSharedRingbufferChild$")
+	cmd.Env = append(os.Environ(), "RINGWORM_SHM_PATH="+path)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start child process: %+v", err)
+	}
+
+	var out bytes.Buffer
+	readBuf := make([]byte, 37)
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		n, err := ringbuf.Read(readBuf)
+		out.Write(readBuf[:n])
+		if err == io.EOF {
+			break
+		}
+		if n == 0 {
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("child process failed: %+v", err)
+	}
+
+	if !bytes.Equal(out.Bytes(), sharedTestData()) {
+		t.Errorf("expected to read same as what the child process wrote\n")
+	}
+}
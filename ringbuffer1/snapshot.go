@@ -0,0 +1,150 @@
+package ringbuffer
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// snapshotVersion is the first byte of a snapshot, bumped whenever the
+// format changes.
+const snapshotVersion = 1
+
+const (
+	snapshotOverwrite = 1 << iota
+	snapshotClosed
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Ringbuffer)(nil)
+	_ encoding.BinaryUnmarshaler = (*Ringbuffer)(nil)
+)
+
+// A SnapshotError is returned when restoring a snapshot that is truncated,
+// corrupt, uses flags this version doesn't know, or is of an unsupported
+// version.
+type SnapshotError struct {
+	Reason string
+}
+
+func (e *SnapshotError) Error() string {
+	return "ringbuffer: invalid snapshot: " + e.Reason
+}
+
+// MarshalBinary encodes the ringbuffer into a snapshot that UnmarshalBinary
+// can restore. The snapshot holds, in order: a version byte, a flags byte
+// (overwrite mode, closed), the capacity and the size as uvarints, the
+// contents from oldest to newest, and a CRC-32 of everything before it.
+//
+// It doesn't consume anything. Take snapshots while the producer and consumer
+// are stopped, or from the consumer goroutine, in which case bytes written
+// concurrently may or may not be included.
+func (r *Ringbuffer) MarshalBinary() ([]byte, error) {
+	r.lockStorage()
+	defer r.unlockStorage()
+
+	first, second := r.PeekRegions()
+
+	var flags byte
+	if r.overwrite {
+		flags |= snapshotOverwrite
+	}
+	if r.isClosed() {
+		flags |= snapshotClosed
+	}
+
+	buf := make([]byte, 0, 2+2*binary.MaxVarintLen64+len(first)+len(second)+crc32.Size)
+	buf = append(buf, snapshotVersion, flags)
+	buf = appendUvarint(buf, uint64(r.Capacity()))
+	buf = appendUvarint(buf, uint64(len(first)+len(second)))
+	buf = append(buf, first...)
+	buf = append(buf, second...)
+
+	var sum [crc32.Size]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(buf))
+	return append(buf, sum[:]...), nil
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	return append(buf, tmp[:n]...)
+}
+
+// UnmarshalBinary replaces the ringbuffer with the one encoded in a snapshot
+// from MarshalBinary: same capacity, mode, closed state and contents. Invalid
+// snapshots are rejected with a *SnapshotError, and leave the ringbuffer
+// untouched.
+//
+// It must not be called while the ringbuffer is in use.
+func (r *Ringbuffer) UnmarshalBinary(data []byte) error {
+	if len(data) < 2+crc32.Size {
+		return &SnapshotError{"truncated"}
+	}
+	if data[0] != snapshotVersion {
+		return &SnapshotError{"unsupported version"}
+	}
+
+	body, sum := data[:len(data)-crc32.Size], data[len(data)-crc32.Size:]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(sum) {
+		return &SnapshotError{"checksum mismatch"}
+	}
+
+	flags := body[1]
+	if flags&^(snapshotOverwrite|snapshotClosed) != 0 {
+		return &SnapshotError{"unknown flags"}
+	}
+	rd := bytes.NewReader(body[2:])
+	capacity, err := binary.ReadUvarint(rd)
+	if err != nil {
+		return &SnapshotError{"truncated capacity"}
+	}
+	size, err := binary.ReadUvarint(rd)
+	if err != nil {
+		return &SnapshotError{"truncated size"}
+	}
+	if capacity == 0 || capacity > maxCapacity {
+		return &SnapshotError{"invalid capacity"}
+	}
+	if size > capacity || size != uint64(rd.Len()) {
+		return &SnapshotError{"size doesn't match contents"}
+	}
+
+	var restored Ringbuffer
+	if flags&snapshotOverwrite != 0 {
+		restored = NewOverwritingRingbuffer(int(capacity))
+	} else {
+		restored = NewRingbuffer(int(capacity))
+	}
+	rd.Read(restored.buf[:size])
+	restored.write = uint32(size)
+	if flags&snapshotClosed != 0 {
+		restored.Close()
+	}
+
+	*r = restored
+	return nil
+}
+
+// WriteSnapshot writes a snapshot of the ringbuffer, as encoded by
+// MarshalBinary, to w, which is typically an *os.File.
+func (r *Ringbuffer) WriteSnapshot(w io.Writer) (int64, error) {
+	data, err := r.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot from rd until EOF,
+// and restores the ringbuffer from it like UnmarshalBinary.
+func (r *Ringbuffer) ReadSnapshot(rd io.Reader) (int64, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return int64(len(data)), err
+	}
+	return int64(len(data)), r.UnmarshalBinary(data)
+}
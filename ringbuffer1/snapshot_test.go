@@ -0,0 +1,141 @@
+package ringbuffer_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+func TestRingbufferSnapshotRoundTrip(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	// leave contents that wrap around the end of storage
+	ringbuf.Write([]byte("abcdef"))
+	ringbuf.Read(make([]byte, 4))
+	ringbuf.Write([]byte("ghij"))
+
+	data, err := ringbuf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Didn't expect error when marshaling: %+v\n", err)
+	}
+	if ringbuf.Size() != 6 {
+		t.Errorf("Expected snapshot to not consume, got size %d", ringbuf.Size())
+	}
+
+	var restored ringbuffer.Ringbuffer
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Didn't expect error when unmarshaling: %+v\n", err)
+	}
+
+	if restored.Capacity() != 8 || restored.Size() != 6 {
+		t.Errorf("Expected capacity 8 and size 6, got %d and %d", restored.Capacity(), restored.Size())
+	}
+
+	// both behave the same from here on
+	for _, r := range []*ringbuffer.Ringbuffer{&ringbuf, &restored} {
+		n, err := r.Write([]byte("klm"))
+		if n != 2 || !errors.Is(err, ringbuffer.ErrFull) {
+			t.Errorf("Expected short write of 2 bytes, got %d, %+v", n, err)
+		}
+		if ret := string(r.Drain()); ret != "efghijkl" {
+			t.Errorf("Expected to drain efghijkl, got %s", ret)
+		}
+	}
+}
+
+func TestRingbufferSnapshotModes(t *testing.T) {
+	ringbuf := ringbuffer.NewOverwritingRingbuffer(4)
+	ringbuf.Write([]byte("ab"))
+	ringbuf.Close()
+
+	data, _ := ringbuf.MarshalBinary()
+
+	var restored ringbuffer.Ringbuffer
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Didn't expect error when unmarshaling: %+v\n", err)
+	}
+
+	if _, err := restored.Write([]byte("c")); err != ringbuffer.ErrClosed {
+		t.Errorf("Expected restored ringbuf to be closed, got %+v", err)
+	}
+
+	restored.Read(make([]byte, 4))
+	if _, err := restored.Read(make([]byte, 4)); err != io.EOF {
+		t.Errorf("Expected io.EOF from restored closed ringbuf, got %+v", err)
+	}
+
+	ringbuf = ringbuffer.NewOverwritingRingbuffer(4)
+	data, _ = ringbuf.MarshalBinary()
+	restored.UnmarshalBinary(data)
+
+	if _, evicted, _ := restored.WriteOverwrite([]byte("abcdef")); evicted != 2 {
+		t.Errorf("Expected restored ringbuf to be in overwrite mode, evicted %d", evicted)
+	}
+}
+
+func TestRingbufferSnapshotInvalid(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(16)
+	ringbuf.Write([]byte("hello, world!"))
+
+	data, _ := ringbuf.MarshalBinary()
+
+	corrupt := append([]byte(nil), data...)
+	corrupt[5] ^= 0xff
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[0] = 0
+
+	// a valid checksum over flags this version doesn't know about
+	badFlags := append([]byte(nil), data...)
+	badFlags[1] |= 0x80
+	body := badFlags[:len(badFlags)-crc32.Size]
+	binary.LittleEndian.PutUint32(badFlags[len(body):], crc32.ChecksumIEEE(body))
+
+	for name, input := range map[string][]byte{
+		"empty":     nil,
+		"truncated": data[:len(data)-1],
+		"corrupt":   corrupt,
+		"version":   badVersion,
+		"flags":     badFlags,
+	} {
+		var restored ringbuffer.Ringbuffer
+		err := restored.UnmarshalBinary(input)
+
+		var snapErr *ringbuffer.SnapshotError
+		if !errors.As(err, &snapErr) {
+			t.Errorf("%s: expected a *SnapshotError, got %+v", name, err)
+		}
+	}
+}
+
+func TestRingbufferSnapshotFile(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(32)
+	ringbuf.Write([]byte("unsent data"))
+
+	f, err := os.CreateTemp("", "ringworm")
+	if err != nil {
+		t.Fatalf("failed to create snapshot file: %+v", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := ringbuf.WriteSnapshot(f); err != nil {
+		t.Fatalf("Didn't expect error when writing snapshot: %+v\n", err)
+	}
+
+	f.Seek(0, io.SeekStart)
+
+	var restored ringbuffer.Ringbuffer
+	if _, err := restored.ReadSnapshot(f); err != nil {
+		t.Fatalf("Didn't expect error when reading snapshot: %+v\n", err)
+	}
+
+	if ret := string(restored.Drain()); ret != "unsent data" {
+		t.Errorf("Expected to restore unsent data, got %s", ret)
+	}
+}
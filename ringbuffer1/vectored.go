@@ -0,0 +1,111 @@
+package ringbuffer
+
+import (
+	"io"
+	"net"
+)
+
+var (
+	_ io.WriterTo   = (*Ringbuffer)(nil)
+	_ io.ReaderFrom = (*Ringbuffer)(nil)
+)
+
+// WriteTo writes the data in the ringbuffer to w until the ringbuffer is
+// empty or an error occurs, consuming what was written. It implements
+// io.WriterTo, so io.Copy uses it.
+//
+// The one or two contiguous regions holding the data are handed to w directly
+// as net.Buffers, without copying them into an intermediate slice. If w is a
+// net.Conn that supports it, both regions go out in a single writev.
+//
+// WriteTo never blocks waiting for more data. In overwrite mode the producer
+// may overwrite the regions at any time, so it copies them out with Read
+// first instead.
+func (r *Ringbuffer) WriteTo(w io.Writer) (n int64, err error) {
+	if r.overwrite {
+		buf := make([]byte, r.Capacity())
+		for {
+			count, _ := r.Read(buf)
+			if count == 0 {
+				return n, nil
+			}
+			written, err := w.Write(buf[:count])
+			n += int64(written)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	for {
+		first, second := r.PeekRegions()
+		if len(first) == 0 {
+			return n, nil
+		}
+
+		bufs := net.Buffers{first}
+		if len(second) > 0 {
+			bufs = append(bufs, second)
+		}
+
+		written, err := bufs.WriteTo(w)
+		n += written
+		r.Consume(int(written))
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// ReadFrom reads data from rd straight into the ringbuffer's free space until
+// EOF or an error occurs. It implements io.ReaderFrom, so io.Copy uses it. An
+// EOF from rd is not returned as an error.
+//
+// If the ringbuffer fills up before rd reports EOF, ReadFrom returns ErrFull,
+// and the rest of the data is left unread in rd. In overwrite mode it never
+// fills up; instead it reads into a temporary buffer and evicts old data like
+// Write.
+func (r *Ringbuffer) ReadFrom(rd io.Reader) (n int64, err error) {
+	if r.isClosed() {
+		return 0, ErrClosed
+	}
+
+	if r.overwrite {
+		buf := make([]byte, r.Capacity())
+		for {
+			count, err := rd.Read(buf)
+			r.Write(buf[:count])
+			n += int64(count)
+			if err == io.EOF {
+				return n, nil
+			}
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+
+	for {
+		// only the producer moves write, so it can be loaded once
+		write := r.writePtr()
+		emptyCount := uint32(r.Capacity() - r.Size())
+		if emptyCount == 0 {
+			return n, ErrFull
+		}
+
+		// fill the region up to the end of storage first, the next pass
+		// gets the region after the wraparound
+		first, _ := r.regions(write, emptyCount)
+		count, err := rd.Read(first)
+		if count > 0 {
+			r.advanceWrite(write, uint32(count))
+			n += int64(count)
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package ringbuffer_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/sevagh/ringworm/ringbuffer1"
+)
+
+// chunkWriter records the slices it is asked to write.
+type chunkWriter struct {
+	chunks []string
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	c.chunks = append(c.chunks, string(p))
+	return len(p), nil
+}
+
+func TestRingbufferWriteToWrapped(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	ringbuf.Write([]byte("abcdef"))
+	ringbuf.Read(make([]byte, 4))
+	ringbuf.Write([]byte("ghij"))
+
+	var w chunkWriter
+	n, err := ringbuf.WriteTo(&w)
+	if n != 6 || err != nil {
+		t.Errorf("Expected to write 6 bytes, got %d, %+v", n, err)
+	}
+
+	// the regions are written as they are, without being joined first
+	if len(w.chunks) != 2 || w.chunks[0] != "efgh" || w.chunks[1] != "ij" {
+		t.Errorf("Expected the two regions efgh and ij, got %q", w.chunks)
+	}
+	if !ringbuf.Empty() {
+		t.Errorf("Expected WriteTo to consume what it wrote")
+	}
+}
+
+func TestRingbufferWriteToConn(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("can't listen on loopback: %+v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %+v", err)
+	}
+
+	ringbuf := ringbuffer.NewRingbuffer(64)
+	var expected bytes.Buffer
+	for i := 0; i < 10; i++ {
+		data := bytes.Repeat([]byte{byte('a' + i)}, 40)
+		ringbuf.Write(data)
+		expected.Write(data)
+
+		if _, err := ringbuf.WriteTo(conn); err != nil {
+			t.Errorf("Didn't expect error writing to conn: %+v\n", err)
+		}
+	}
+	conn.Close()
+
+	if data := <-received; !bytes.Equal(data, expected.Bytes()) {
+		t.Errorf("Expected the conn to receive everything written to the ringbuf")
+	}
+}
+
+func TestRingbufferReadFrom(t *testing.T) {
+	ringbuf := ringbuffer.NewRingbuffer(8)
+
+	ringbuf.Write([]byte("abcdef"))
+	ringbuf.Read(make([]byte, 5))
+
+	// fills the end of storage, then wraps around
+	n, err := ringbuf.ReadFrom(strings.NewReader("ghijk"))
+	if n != 5 || err != nil {
+		t.Errorf("Expected to read 5 bytes until EOF, got %d, %+v", n, err)
+	}
+
+	src := strings.NewReader("lmnopq")
+	n, err = ringbuf.ReadFrom(src)
+	if n != 2 || !errors.Is(err, ringbuffer.ErrFull) {
+		t.Errorf("Expected to fill the ringbuf with 2 bytes, got %d, %+v", n, err)
+	}
+	if src.Len() != 4 {
+		t.Errorf("Expected the rest to stay in the reader, got %d bytes left", src.Len())
+	}
+
+	if ret := string(ringbuf.Drain()); ret != "fghijklm" {
+		t.Errorf("Expected to drain fghijklm, got %s", ret)
+	}
+}
+
+func TestRingbufferReadFromOverwrite(t *testing.T) {
+	ringbuf := ringbuffer.NewOverwritingRingbuffer(4)
+
+	n, err := io.Copy(&ringbuf, strings.NewReader("hello, world!"))
+	if n != 13 || err != nil {
+		t.Errorf("Expected to read everything, got %d, %+v", n, err)
+	}
+
+	var out bytes.Buffer
+	ringbuf.WriteTo(&out)
+	if out.String() != "rld!" {
+		t.Errorf("Expected the last 4 bytes, got %s", out.String())
+	}
+}
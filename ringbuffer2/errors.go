@@ -0,0 +1,35 @@
+package ringbuffer
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrFull is returned when inserting into a full ring. Inserts that don't
+	// fit return a *CapacityError, which matches ErrFull with errors.Is.
+	//
+	// Unlike ringbuffer1, there is no ErrClosed to go with it, since the
+	// rings here can't be closed.
+	ErrFull = errors.New("ringbuffer: full")
+
+	// ErrEmpty is returned when popping from an empty ring.
+	ErrEmpty = errors.New("ringbuffer: empty")
+)
+
+// A CapacityError is returned by inserts that don't fit in the ring. It
+// matches ErrFull with errors.Is, and can be unpacked with errors.As to find
+// out how much space there was.
+type CapacityError struct {
+	Requested int // values the caller tried to insert
+	Available int // free slots at the time
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("ringbuffer: insert of %d values is too big for remaining capacity %d", e.Requested, e.Available)
+}
+
+// Is reports whether target is ErrFull.
+func (e *CapacityError) Is(target error) bool {
+	return target == ErrFull
+}
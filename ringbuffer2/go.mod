@@ -1,3 +1,3 @@
 module github.com/sevagh/ringworm/ringbuffer2
 
-go 1.13
+go 1.23
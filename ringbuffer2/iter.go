@@ -0,0 +1,97 @@
+package ringbuffer
+
+import "iter"
+
+// All returns an iterator over the values in the ring, from oldest to
+// newest, paired with their index from the tail, as used by At.
+//
+// The ring may be modified in the loop body. Each step yields At(i) for the
+// next i, checked against the current Size, so after a Pop the iteration
+// skips what was the next value, and values inserted at the head are
+// visited once it gets to them.
+func (r *Ring[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := 0; i < r.size; i++ {
+			if !yield(i, r.storage[r.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward is like All, but iterates from newest to oldest. If the ring
+// shrinks in the loop body, the iteration continues from the new newest
+// value if it's older than the one it was going to yield.
+func (r *Ring[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i := r.size - 1; i >= 0; i-- {
+			if i >= r.size {
+				i = r.size - 1
+				if i < 0 {
+					return
+				}
+			}
+			if !yield(i, r.storage[r.index(i)]) {
+				return
+			}
+		}
+	}
+}
+
+// Draining returns an iterator that pops each value before yielding it,
+// until the ring is empty. Values inserted in the loop body are drained too,
+// and breaking out of the loop leaves the rest in the ring.
+func (r *Ring[T]) Draining() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for !r.Empty() {
+			val, _ := r.Pop()
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the elems in the ringbuffer, from oldest to
+// newest, paired with their index from the tail. It behaves like Ring.All
+// when the ringbuffer is modified mid-iteration.
+func (r *ringBuffer) All() iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for i := 0; i < r.Size(); i++ {
+			if !yield(i, (*r.storage)[(r.tail+uint(i))&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
+// Backward is like All, but iterates from newest to oldest, like
+// Ring.Backward.
+func (r *ringBuffer) Backward() iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for i := r.Size() - 1; i >= 0; i-- {
+			if i >= r.Size() {
+				i = r.Size() - 1
+				if i < 0 {
+					return
+				}
+			}
+			if !yield(i, (*r.storage)[(r.tail+uint(i))&r.mask]) {
+				return
+			}
+		}
+	}
+}
+
+// Draining returns an iterator that pops each elem before yielding it, like
+// Ring.Draining.
+func (r *ringBuffer) Draining() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for !r.Empty() {
+			val, _ := r.Pop()
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package ringbuffer
+
+import "testing"
+
+func TestRingAll(t *testing.T) {
+	r := NewRing[int](4)
+	r.InsertN([]int{-1, -1, -1})
+	r.PopN(make([]int, 3))
+	r.InsertN([]int{0, 1, 2, 3})
+
+	next := 0
+	for i, v := range r.All() {
+		if i != next || v != next {
+			t.Errorf("Expected %d at %d, got %d at %d", next, next, v, i)
+		}
+		next++
+	}
+	if next != 4 {
+		t.Errorf("Expected to visit 4 values, got %d", next)
+	}
+
+	next = 3
+	for i, v := range r.Backward() {
+		if i != next || v != next {
+			t.Errorf("Expected %d at %d, got %d at %d", next, next, v, i)
+		}
+		next--
+	}
+
+	if r.Size() != 4 {
+		t.Errorf("Expected iterating not to remove anything, got size %d", r.Size())
+	}
+}
+
+func TestRingBackwardShrinking(t *testing.T) {
+	r := NewRing[int](4)
+	r.InsertN([]int{0, 1, 2, 3})
+
+	var got []int
+	for _, v := range r.Backward() {
+		got = append(got, v)
+		r.PopBack()
+		r.PopBack()
+	}
+	if len(got) != 2 || got[0] != 3 || got[1] != 1 {
+		t.Errorf("Expected to visit 3 then 1, got %v", got)
+	}
+}
+
+func TestRingDraining(t *testing.T) {
+	r := NewRing[string](3)
+	r.Insert("a")
+	r.Insert("b")
+
+	var got []string
+	for v := range r.Draining() {
+		got = append(got, v)
+		if v == "a" {
+			r.Insert("c")
+		}
+	}
+	if len(got) != 3 || got[2] != "c" || !r.Empty() {
+		t.Errorf("Expected to drain a b c, got %v", got)
+	}
+}
+
+func TestRingBufferIterators(t *testing.T) {
+	rb := NewRingBufferWithCapacity(4)
+	rb.InsertN([]int{0, 1, 2})
+
+	var got []int
+	for i, v := range rb.All() {
+		got = append(got, v)
+		if i == 0 {
+			rb.Insert(3)
+		}
+	}
+	if len(got) != 4 || got[3] != 3 {
+		t.Errorf("Expected All to visit the elem inserted mid-iteration, got %v", got)
+	}
+
+	got = got[:0]
+	for _, v := range rb.Backward() {
+		got = append(got, v)
+	}
+	if len(got) != 4 || got[0] != 3 || got[3] != 0 {
+		t.Errorf("Expected Backward to visit 3 2 1 0, got %v", got)
+	}
+
+	got = got[:0]
+	for v := range rb.Draining() {
+		got = append(got, v)
+		if v == 1 {
+			break
+		}
+	}
+	if len(got) != 2 || rb.Size() != 2 {
+		t.Errorf("Expected to drain 0 1 and leave 2 elems, got %v and size %d", got, rb.Size())
+	}
+}
@@ -0,0 +1,239 @@
+package ringbuffer
+
+// Ring is a FIFO ringbuffer of values of type T, with a capacity chosen by
+// the caller. Values are stored unboxed in a []T.
+//
+// Like ringBuffer, it is not safe for concurrent use.
+type Ring[T any] struct {
+	storage []T
+	head    int
+	tail    int
+	size    int
+}
+
+// NewRing creates a Ring that holds up to capacity values. It panics if
+// capacity is not positive.
+func NewRing[T any](capacity int) *Ring[T] {
+	if capacity <= 0 {
+		panic("ringbuffer: capacity must be positive")
+	}
+
+	return &Ring[T]{
+		storage: make([]T, capacity),
+	}
+}
+
+// split returns the count slots of storage starting at idx as one or two
+// contiguous runs, the second one wrapping around to the start of storage.
+func split[T any](storage []T, idx, count int) (first, second []T) {
+	end := idx + count
+	if end <= len(storage) {
+		return storage[idx:end], nil
+	}
+	return storage[idx:], storage[:end-len(storage)]
+}
+
+// next returns the storage index after i, wrapping around.
+func (r *Ring[T]) next(i int) int {
+	i++
+	if i == len(r.storage) {
+		return 0
+	}
+	return i
+}
+
+// prev returns the storage index before i, wrapping around.
+func (r *Ring[T]) prev(i int) int {
+	if i == 0 {
+		return len(r.storage) - 1
+	}
+	return i - 1
+}
+
+// index returns the storage index of the i-th value from the tail.
+func (r *Ring[T]) index(i int) int {
+	return (r.tail + i) % len(r.storage)
+}
+
+// InsertWithError adds val at the head, or returns a *CapacityError if the
+// ring is full.
+func (r *Ring[T]) InsertWithError(val T) error {
+	if r.Full() {
+		return &CapacityError{Requested: 1, Available: 0}
+	}
+	r.Insert(val)
+	return nil
+}
+
+// Insert adds val at the head. If the ring is full, the oldest value is
+// evicted to make room, and returned with evicted set to true.
+func (r *Ring[T]) Insert(val T) (old T, evicted bool) {
+	if r.size == len(r.storage) {
+		old = r.storage[r.head]
+		evicted = true
+	}
+	r.storage[r.head] = val
+	r.head = r.next(r.head)
+	if evicted {
+		r.tail = r.head
+		return old, evicted
+	}
+	r.size++
+	return old, evicted
+}
+
+// Pop removes and returns the oldest value, or returns ErrEmpty if the ring
+// is empty.
+func (r *Ring[T]) Pop() (T, error) {
+	var zero T
+	if r.Empty() {
+		return zero, ErrEmpty
+	}
+	ret := r.storage[r.tail]
+	// don't keep popped values reachable
+	r.storage[r.tail] = zero
+	r.tail = r.next(r.tail)
+	r.size--
+	return ret, nil
+}
+
+// Peek returns the oldest value without removing it. If the ring is empty it
+// returns the zero value and false.
+func (r *Ring[T]) Peek() (T, bool) {
+	if r.Empty() {
+		var zero T
+		return zero, false
+	}
+	return r.storage[r.tail], true
+}
+
+// PeekN copies up to len(dst) of the oldest values into dst, oldest first,
+// without removing them, and returns how many were copied.
+func (r *Ring[T]) PeekN(dst []T) int {
+	n := len(dst)
+	if r.size < n {
+		n = r.size
+	}
+	first, second := split(r.storage, r.tail, n)
+	copy(dst, first)
+	copy(dst[len(first):], second)
+	return n
+}
+
+// PopN removes up to len(dst) of the oldest values and copies them into dst,
+// oldest first. It returns how many were removed.
+func (r *Ring[T]) PopN(dst []T) int {
+	n := r.PeekN(dst)
+
+	// don't keep popped values reachable
+	var zero T
+	first, second := split(r.storage, r.tail, n)
+	for i := range first {
+		first[i] = zero
+	}
+	for i := range second {
+		second[i] = zero
+	}
+
+	r.tail = (r.tail + n) % len(r.storage)
+	r.size -= n
+	return n
+}
+
+// InsertN adds as many values from src at the head as there is room for, and
+// returns how many were added. Unlike Insert it never evicts: if not all of
+// src fits, the values that do are added and a *CapacityError is returned.
+func (r *Ring[T]) InsertN(src []T) (n int, err error) {
+	n = len(src)
+	if available := len(r.storage) - r.size; n > available {
+		err = &CapacityError{Requested: n, Available: available}
+		n = available
+	}
+
+	first, second := split(r.storage, r.head, n)
+	copy(first, src)
+	copy(second, src[len(first):])
+
+	r.head = (r.head + n) % len(r.storage)
+	r.size += n
+	return n, err
+}
+
+// Max returns the number of values the ring can hold.
+func (r *Ring[T]) Max() int {
+	return len(r.storage)
+}
+
+// Empty returns true if the ring holds no values.
+func (r *Ring[T]) Empty() bool {
+	return r.size == 0
+}
+
+// Size returns the number of values in the ring.
+func (r *Ring[T]) Size() int {
+	return r.size
+}
+
+// Full returns true if the ring holds Max() values.
+func (r *Ring[T]) Full() bool {
+	return r.size == len(r.storage)
+}
+
+// PushFront adds val at the tail, so that it is the next value to be popped.
+// If the ring is full, the newest value is evicted to make room, and
+// returned with evicted set to true.
+func (r *Ring[T]) PushFront(val T) (old T, evicted bool) {
+	if r.size == len(r.storage) {
+		old, _ = r.PopBack()
+		evicted = true
+	}
+	r.tail = r.prev(r.tail)
+	r.storage[r.tail] = val
+	r.size++
+	return old, evicted
+}
+
+// PopBack removes and returns the newest value, or returns ErrEmpty if the
+// ring is empty.
+func (r *Ring[T]) PopBack() (T, error) {
+	var zero T
+	if r.Empty() {
+		return zero, ErrEmpty
+	}
+	r.head = r.prev(r.head)
+	ret := r.storage[r.head]
+	// don't keep popped values reachable
+	r.storage[r.head] = zero
+	r.size--
+	return ret, nil
+}
+
+// PeekBack returns the newest value without removing it. If the ring is
+// empty it returns the zero value and false.
+func (r *Ring[T]) PeekBack() (T, bool) {
+	if r.Empty() {
+		var zero T
+		return zero, false
+	}
+	return r.storage[r.prev(r.head)], true
+}
+
+// At returns the i-th value counted from the tail, so At(0) is the oldest
+// value. It returns the zero value and false if i is out of range.
+func (r *Ring[T]) At(i int) (T, bool) {
+	if i < 0 || i >= r.size {
+		var zero T
+		return zero, false
+	}
+	return r.storage[r.index(i)], true
+}
+
+// Set replaces the i-th value counted from the tail with val. It returns
+// false, and changes nothing, if i is out of range.
+func (r *Ring[T]) Set(i int, val T) bool {
+	if i < 0 || i >= r.size {
+		return false
+	}
+	r.storage[r.index(i)] = val
+	return true
+}
@@ -0,0 +1,179 @@
+package ringbuffer
+
+import (
+	"errors"
+	"testing"
+)
+
+type point struct {
+	x, y int
+}
+
+func TestRingInsertPop(t *testing.T) {
+	r := NewRing[point](3)
+
+	for i := 0; i < 3; i++ {
+		if err := r.InsertWithError(point{i, -i}); err != nil {
+			t.Errorf("Didn't expect error inserting elem %d: %s", i, err.Error())
+		}
+	}
+	if !r.Full() || r.Size() != 3 {
+		t.Errorf("Expected ring to be full with size 3, got size %d", r.Size())
+	}
+	err := r.InsertWithError(point{3, -3})
+	if !errors.Is(err, ErrFull) {
+		t.Errorf("Expected ErrFull inserting into full ring, got %+v", err)
+	}
+	var capErr *CapacityError
+	if !errors.As(err, &capErr) || capErr.Requested != 1 || capErr.Available != 0 {
+		t.Errorf("Expected a CapacityError with no space available, got %+v", err)
+	}
+
+	if p, ok := r.Peek(); !ok || p != (point{0, 0}) {
+		t.Errorf("Expected to peek the oldest elem, got %+v", p)
+	}
+
+	for i := 0; i < 3; i++ {
+		p, err := r.Pop()
+		if err != nil {
+			t.Errorf("Didn't expect error popping elem %d: %s", i, err.Error())
+		}
+		if p != (point{i, -i}) {
+			t.Errorf("Expected to pop %+v, got %+v", point{i, -i}, p)
+		}
+	}
+
+	if _, err := r.Pop(); err != ErrEmpty || !r.Empty() {
+		t.Errorf("Expected ErrEmpty popping from empty ring, got %+v", err)
+	}
+}
+
+func TestRingInsertOverwritesOldest(t *testing.T) {
+	r := NewRing[string](2)
+
+	r.Insert("a")
+	r.Insert("b")
+	if old, evicted := r.Insert("c"); !evicted || old != "a" {
+		t.Errorf("Expected inserting into a full ring to evict a, got %s, %t", old, evicted)
+	}
+
+	if r.Size() != 2 {
+		t.Errorf("Expected size to stay at capacity, got %d", r.Size())
+	}
+
+	for _, exp := range []string{"b", "c"} {
+		got, err := r.Pop()
+		if err != nil || got != exp {
+			t.Errorf("Expected to pop %s, got %s, %v", exp, got, err)
+		}
+	}
+}
+
+func TestRingWraparound(t *testing.T) {
+	r := NewRing[int](5)
+	var expected []int
+
+	for i := 0; i < 100; i++ {
+		r.Insert(i)
+		expected = append(expected, i)
+
+		// pop one for every two inserts while there's room, so the head and
+		// tail chase each other around the storage
+		if i%2 == 1 || r.Full() {
+			got, err := r.Pop()
+			if err != nil || got != expected[0] {
+				t.Errorf("Expected to pop %d, got %d, %v", expected[0], got, err)
+			}
+			expected = expected[1:]
+		}
+
+		if r.Size() != len(expected) {
+			t.Errorf("Expected size %d, got %d", len(expected), r.Size())
+		}
+	}
+}
+
+func TestRingPeekEmpty(t *testing.T) {
+	r := NewRing[int](2)
+
+	if _, ok := r.Peek(); ok {
+		t.Errorf("Expected Peek on an empty ring to return false")
+	}
+	r.Insert(1)
+	r.Pop()
+	if v, ok := r.Peek(); ok || v != 0 {
+		t.Errorf("Expected Peek after popping everything to return 0, false, got %d, %t", v, ok)
+	}
+}
+
+func TestRingBatch(t *testing.T) {
+	r := NewRing[int](5)
+	r.InsertN([]int{-1, -2, -3})
+	r.PopN(make([]int, 3))
+
+	// the run of values wraps around the end of storage
+	n, err := r.InsertN([]int{0, 1, 2, 3, 4, 5, 6})
+	if n != 5 || !errors.Is(err, ErrFull) {
+		t.Errorf("Expected to insert 5 values and get ErrFull, got %d, %v", n, err)
+	}
+
+	peeked := make([]int, 3)
+	if n := r.PeekN(peeked); n != 3 || peeked[0] != 0 || peeked[2] != 2 {
+		t.Errorf("Expected to peek 0 1 2, got %v", peeked[:n])
+	}
+
+	popped := make([]int, 8)
+	n = r.PopN(popped)
+	if n != 5 {
+		t.Errorf("Expected to pop 5 values, got %d", n)
+	}
+	for i := 0; i < n; i++ {
+		if popped[i] != i {
+			t.Errorf("Expected to pop %d at %d, got %d", i, i, popped[i])
+		}
+	}
+	if !r.Empty() {
+		t.Errorf("Expected the ring to be empty, got size %d", r.Size())
+	}
+}
+
+func TestRingDeque(t *testing.T) {
+	r := NewRing[string](3)
+
+	r.Insert("b")
+	r.PushFront("a")
+	r.Insert("c")
+
+	for i, exp := range []string{"a", "b", "c"} {
+		if v, ok := r.At(i); !ok || v != exp {
+			t.Errorf("Expected %s at %d, got %s, %t", exp, i, v, ok)
+		}
+	}
+	if _, ok := r.At(3); ok {
+		t.Errorf("Expected At past the size to return false")
+	}
+
+	// pushing onto a full ring evicts from the other end
+	if old, evicted := r.PushFront("z"); !evicted || old != "c" {
+		t.Errorf("Expected PushFront to evict c, got %s, %t", old, evicted)
+	}
+
+	if !r.Set(1, "A") || r.Set(-1, "x") {
+		t.Errorf("Expected Set to succeed only in range")
+	}
+	if v, ok := r.PeekBack(); !ok || v != "b" {
+		t.Errorf("Expected to peek back b, got %s, %t", v, ok)
+	}
+
+	for _, exp := range []string{"b", "A", "z"} {
+		if v, err := r.PopBack(); err != nil || v != exp {
+			t.Errorf("Expected to pop back %s, got %s, %v", exp, v, err)
+		}
+	}
+	if _, err := r.PopBack(); err != ErrEmpty {
+		t.Errorf("Expected ErrEmpty popping back from an empty ring, got %v", err)
+	}
+	if _, ok := r.PeekBack(); ok {
+		t.Errorf("Expected PeekBack on an empty ring to return false")
+	}
+}
@@ -1,21 +1,50 @@
 package ringbuffer
 
-import "fmt"
+import "math"
 
+// ringBuffer stores ints in power-of-two sized storage. head and tail run
+// freely and are masked to index the storage, so they wrap around on their
+// own like the original uint8 indices did, but for any capacity.
 type ringBuffer struct {
 	storage *[]int
 	max     int
-	head    uint8
-	tail    uint8
+	mask    uint
+	head    uint
+	tail    uint
 }
 
+// NewRingBuffer creates a ringBuffer with 256 slots, holding up to 255 ints.
 func NewRingBuffer() *ringBuffer {
-	max := int(^uint8(0))
-	storage := make([]int, max+1)
+	r := NewRingBufferWithCapacity(int(^uint8(0)) + 1)
+	// one slot stays free, as when head and tail were uint8s
+	r.max--
+	return r
+}
+
+// maxCapacity is the largest power of two an int can hold.
+const maxCapacity = math.MaxInt/2 + 1
+
+// NewRingBufferWithCapacity creates a ringBuffer holding up to capacity ints,
+// rounded up to the next power of two. It panics if capacity is not positive,
+// or too large to round up.
+func NewRingBufferWithCapacity(capacity int) *ringBuffer {
+	if capacity <= 0 {
+		panic("ringbuffer: capacity must be positive")
+	}
+	if capacity > maxCapacity {
+		panic("ringbuffer: capacity too large")
+	}
+
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	storage := make([]int, size)
 
 	return &ringBuffer{
 		storage: &storage,
-		max:     max,
+		max:     size,
+		mask:    uint(size - 1),
 		head:    0,
 		tail:    0,
 	}
@@ -23,30 +52,82 @@ func NewRingBuffer() *ringBuffer {
 
 func (r *ringBuffer) InsertWithError(val int) error {
 	if r.Full() {
-		return fmt.Errorf("Ringbuffer is full!")
+		return &CapacityError{Requested: 1, Available: 0}
 	}
-	(*r.storage)[r.head] = val
+	(*r.storage)[r.head&r.mask] = val
 	r.head += 1
 	return nil
 }
 
-func (r *ringBuffer) Insert(val int) {
-	(*r.storage)[r.head] = val
+// Insert adds val at the head. If the ringbuffer is full, the oldest elem is
+// evicted to make room, and returned with evicted set to true, so that Size
+// stays at Max.
+func (r *ringBuffer) Insert(val int) (old int, evicted bool) {
+	if r.Full() {
+		old = (*r.storage)[r.tail&r.mask]
+		evicted = true
+		r.tail += 1
+	}
+	(*r.storage)[r.head&r.mask] = val
 	r.head += 1
+	return old, evicted
 }
 
 func (r *ringBuffer) Pop() (int, error) {
 	if r.Empty() {
-		return -1, fmt.Errorf("Ringbuffer is empty!")
+		return 0, ErrEmpty
 	}
-	ret := (*r.storage)[r.tail]
+	ret := (*r.storage)[r.tail&r.mask]
 	r.tail += 1
 	return ret, nil
 }
 
-func (r *ringBuffer) Peek() int {
-	ret := (*r.storage)[r.tail]
-	return ret
+// Peek returns the oldest elem without removing it, or false if the
+// ringbuffer is empty.
+func (r *ringBuffer) Peek() (int, bool) {
+	if r.Empty() {
+		return 0, false
+	}
+	return (*r.storage)[r.tail&r.mask], true
+}
+
+// PeekN copies up to len(dst) of the oldest elems into dst, oldest first,
+// without removing them, and returns how many were copied. The elems are
+// copied in at most two runs, either side of the end of storage.
+func (r *ringBuffer) PeekN(dst []int) int {
+	n := len(dst)
+	if size := r.Size(); size < n {
+		n = size
+	}
+	first, second := split(*r.storage, int(r.tail&r.mask), n)
+	copy(dst, first)
+	copy(dst[len(first):], second)
+	return n
+}
+
+// PopN removes up to len(dst) of the oldest elems and copies them into dst,
+// oldest first. It returns how many were removed.
+func (r *ringBuffer) PopN(dst []int) int {
+	n := r.PeekN(dst)
+	r.tail += uint(n)
+	return n
+}
+
+// InsertN adds as many elems from src at the head as there is room for, and
+// returns how many were added. Unlike Insert it never evicts: if not all of
+// src fits, the elems that do are added and a *CapacityError is returned.
+func (r *ringBuffer) InsertN(src []int) (n int, err error) {
+	n = len(src)
+	if available := r.max - r.Size(); n > available {
+		err = &CapacityError{Requested: n, Available: available}
+		n = available
+	}
+
+	first, second := split(*r.storage, int(r.head&r.mask), n)
+	copy(first, src)
+	copy(second, src[len(first):])
+	r.head += uint(n)
+	return n, err
 }
 
 func (r *ringBuffer) Max() int {
@@ -64,3 +145,54 @@ func (r *ringBuffer) Size() int {
 func (r *ringBuffer) Full() bool {
 	return r.Size() == r.max
 }
+
+// PushFront adds val at the tail, so that it is the next elem to be popped.
+// If the ringbuffer is full, the newest elem is evicted to make room, and
+// returned with evicted set to true.
+func (r *ringBuffer) PushFront(val int) (old int, evicted bool) {
+	if r.Full() {
+		old, _ = r.PopBack()
+		evicted = true
+	}
+	r.tail -= 1
+	(*r.storage)[r.tail&r.mask] = val
+	return old, evicted
+}
+
+// PopBack removes and returns the newest elem, or returns ErrEmpty if the
+// ringbuffer is empty.
+func (r *ringBuffer) PopBack() (int, error) {
+	if r.Empty() {
+		return 0, ErrEmpty
+	}
+	r.head -= 1
+	return (*r.storage)[r.head&r.mask], nil
+}
+
+// PeekBack returns the newest elem without removing it, or false if the
+// ringbuffer is empty.
+func (r *ringBuffer) PeekBack() (int, bool) {
+	if r.Empty() {
+		return 0, false
+	}
+	return (*r.storage)[(r.head-1)&r.mask], true
+}
+
+// At returns the i-th elem counted from the tail, so At(0) is the oldest
+// elem. It returns false if i is out of range.
+func (r *ringBuffer) At(i int) (int, bool) {
+	if i < 0 || i >= r.Size() {
+		return 0, false
+	}
+	return (*r.storage)[(r.tail+uint(i))&r.mask], true
+}
+
+// Set replaces the i-th elem counted from the tail with val. It returns
+// false, and changes nothing, if i is out of range.
+func (r *ringBuffer) Set(i int, val int) bool {
+	if i < 0 || i >= r.Size() {
+		return false
+	}
+	(*r.storage)[(r.tail+uint(i))&r.mask] = val
+	return true
+}
@@ -1,6 +1,9 @@
 package ringbuffer
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestInsertions(t *testing.T) {
 	rb := NewRingBuffer()
@@ -31,3 +34,196 @@ func TestInsertions(t *testing.T) {
 		}
 	}
 }
+
+func TestNewRingBufferUnchanged(t *testing.T) {
+	rb := NewRingBuffer()
+	if rb.Max() != 255 {
+		t.Errorf("Expected the default ringbuffer to hold 255 elems, got %d", rb.Max())
+	}
+
+	for i := 0; i < 255; i++ {
+		rb.InsertWithError(i)
+	}
+	if !rb.Full() || rb.InsertWithError(255) == nil {
+		t.Errorf("Expected the default ringbuffer to be full after 255 elems")
+	}
+}
+
+func TestRingBufferWithCapacity(t *testing.T) {
+	rb := NewRingBufferWithCapacity(1000)
+	if rb.Max() != 1024 {
+		t.Errorf("Expected capacity to round up to 1024, got %d", rb.Max())
+	}
+
+	// go around the storage several times, with the ringbuffer half full
+	next := 0
+	for i := 0; i < 10000; i++ {
+		if err := rb.InsertWithError(i); err != nil {
+			t.Fatalf("Didn't expect error inserting elem %d: %s", i, err.Error())
+		}
+		if rb.Size() > 512 {
+			pop, err := rb.Pop()
+			if err != nil || pop != next {
+				t.Fatalf("Expected to pop %d, got %d, %v", next, pop, err)
+			}
+			next++
+		}
+	}
+
+	for i := 0; i < 1024-512; i++ {
+		rb.InsertWithError(i)
+	}
+	if !rb.Full() {
+		t.Errorf("Expected ringbuffer to use its whole capacity, got size %d", rb.Size())
+	}
+}
+
+func TestInsertEvictsOldest(t *testing.T) {
+	rb := NewRingBufferWithCapacity(4)
+
+	for i := 0; i < 4; i++ {
+		if _, evicted := rb.Insert(i); evicted {
+			t.Errorf("Didn't expect inserting elem %d to evict", i)
+		}
+	}
+
+	// a full ringbuffer stays full instead of wrapping around to empty
+	for i := 4; i < 10; i++ {
+		old, evicted := rb.Insert(i)
+		if !evicted || old != i-4 {
+			t.Errorf("Expected inserting %d to evict %d, got %d, %t", i, i-4, old, evicted)
+		}
+		if rb.Size() != rb.Max() {
+			t.Errorf("Expected size to stay at %d, got %d", rb.Max(), rb.Size())
+		}
+	}
+
+	for i := 6; i < 10; i++ {
+		if pop, err := rb.Pop(); err != nil || pop != i {
+			t.Errorf("Expected to pop %d, got %d, %v", i, pop, err)
+		}
+	}
+}
+
+func TestDefaultInsertEvictsOldest(t *testing.T) {
+	rb := NewRingBuffer()
+
+	for i := 0; i < 300; i++ {
+		rb.Insert(i)
+	}
+	if rb.Size() != 255 {
+		t.Errorf("Expected size to stay at 255, got %d", rb.Size())
+	}
+	if pop, _ := rb.Pop(); pop != 300-255 {
+		t.Errorf("Expected to pop the oldest remaining elem %d, got %d", 300-255, pop)
+	}
+}
+
+func TestPeekEmpty(t *testing.T) {
+	rb := NewRingBuffer()
+
+	if _, ok := rb.Peek(); ok {
+		t.Errorf("Expected Peek on an empty ringbuffer to return false")
+	}
+	if pop, err := rb.Pop(); pop != 0 || err != ErrEmpty {
+		t.Errorf("Expected Pop on an empty ringbuffer to return 0, ErrEmpty, got %d, %v", pop, err)
+	}
+
+	rb.Insert(7)
+	if v, ok := rb.Peek(); !ok || v != 7 {
+		t.Errorf("Expected to peek 7, got %d, %t", v, ok)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	rb := NewRingBuffer()
+	src := make([]int, 300)
+	for i := range src {
+		src[i] = i
+	}
+
+	n, err := rb.InsertN(src)
+	if n != 255 || !errors.Is(err, ErrFull) {
+		t.Errorf("Expected to insert 255 elems and get ErrFull, got %d, %v", n, err)
+	}
+
+	// leave the tail near the end of storage, so the next runs wrap around
+	dst := make([]int, 200)
+	rb.PopN(dst)
+	rb.InsertN(src[255:])
+
+	dst = make([]int, 100)
+	if n := rb.PeekN(dst); n != 100 || dst[0] != 200 || dst[99] != 299 {
+		t.Errorf("Expected to peek 200..299, got %d elems", n)
+	}
+	if rb.Size() != 100 {
+		t.Errorf("Expected PeekN not to remove anything, got size %d", rb.Size())
+	}
+
+	if n := rb.PopN(dst); n != 100 {
+		t.Errorf("Expected to pop 100 elems, got %d", n)
+	}
+	for i, v := range dst {
+		if v != 200+i {
+			t.Errorf("Expected to pop %d, got %d", 200+i, v)
+		}
+	}
+	if !rb.Empty() {
+		t.Errorf("Expected the ringbuffer to be empty, got size %d", rb.Size())
+	}
+}
+
+func TestDeque(t *testing.T) {
+	rb := NewRingBufferWithCapacity(4)
+
+	// used as a stack from the tail, so the tail wraps below zero
+	for i := 0; i < 4; i++ {
+		rb.PushFront(i)
+	}
+	if old, evicted := rb.PushFront(4); !evicted || old != 0 {
+		t.Errorf("Expected PushFront onto a full ringbuffer to evict 0, got %d, %t", old, evicted)
+	}
+
+	for i, exp := range []int{4, 3, 2, 1} {
+		if v, ok := rb.At(i); !ok || v != exp {
+			t.Errorf("Expected %d at %d, got %d, %t", exp, i, v, ok)
+		}
+	}
+	if _, ok := rb.At(4); ok {
+		t.Errorf("Expected At past the size to return false")
+	}
+
+	rb.Set(3, 10)
+	if v, ok := rb.PeekBack(); !ok || v != 10 {
+		t.Errorf("Expected to peek back 10, got %d, %t", v, ok)
+	}
+
+	for _, exp := range []int{10, 2, 3, 4} {
+		if v, err := rb.PopBack(); err != nil || v != exp {
+			t.Errorf("Expected to pop back %d, got %d, %v", exp, v, err)
+		}
+	}
+	if _, err := rb.PopBack(); err != ErrEmpty {
+		t.Errorf("Expected ErrEmpty popping back from an empty ringbuffer, got %v", err)
+	}
+}
+
+func TestRingBufferCapacityTooLarge(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a capacity that can't be rounded up to panic")
+		}
+	}()
+	NewRingBufferWithCapacity(maxCapacity + 1)
+}
+
+func TestInsertKeepsSizeAtMax(t *testing.T) {
+	rb := NewRingBuffer()
+
+	for i := 0; i < 300; i++ {
+		rb.Insert(i)
+	}
+	if rb.Size() != rb.Max() {
+		t.Errorf("Expected size to stay at %d, got %d", rb.Max(), rb.Size())
+	}
+}